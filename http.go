@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"expvar"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -23,8 +24,8 @@ func (app *application) mount() *gin.Engine {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{app.config.WebURL},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-CSRF-Token"},
-		ExposeHeaders:    []string{"Content-Length", "Link"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-CSRF-Token", "If-Match"},
+		ExposeHeaders:    []string{"Content-Length", "Link", "ETag", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -39,6 +40,8 @@ func (app *application) mount() *gin.Engine {
 		api.GET("/health", app.handler.BasicAuthMiddleware, app.handler.HealthCheckHandler)
 		api.GET("/debug/vars", app.handler.BasicAuthMiddleware, gin.WrapH(expvar.Handler()))
 		api.Any("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+		api.GET("/.well-known/jwks.json", app.handler.JWKSHandler)
+		api.GET("/search", app.handler.AuthTokenMiddleware, app.handler.SearchHandler)
 		users := api.Group("/users")
 		{
 			users.PUT("/activate/:token", app.handler.ActivateUserHandler)
@@ -47,17 +50,62 @@ func (app *application) mount() *gin.Engine {
 				userfeed.Use(app.handler.AuthTokenMiddleware)
 				userfeed.GET("/", app.handler.GetUserFeedHandler)
 			}
+			followRequests := users.Group("/follow-requests")
+			{
+				followRequests.Use(app.handler.AuthTokenMiddleware)
+				followRequests.GET("/", app.handler.ListFollowRequestsHandler)
+				followRequests.PUT("/:id", app.handler.ApproveFollowRequestHandler)
+				followRequests.DELETE("/:id", app.handler.RejectFollowRequestHandler)
+			}
 			usersID := users.Group("/:id")
 			{
 				usersID.Use(app.handler.AuthTokenMiddleware)
 				usersID.GET("/", app.handler.GetUserHandler)
+				usersID.PATCH("/", app.handler.UpdateUserHandler)
 				usersID.PUT("/follow", app.handler.FollowUserHandler)
 				usersID.PUT("/unfollow", app.handler.UnfollowUserHandler)
+				usersID.PUT("/block", app.handler.BlockUserHandler)
+				usersID.DELETE("/block", app.handler.UnblockUserHandler)
+				usersID.PUT("/mute", app.handler.MuteUserHandler)
+				usersID.DELETE("/mute", app.handler.UnmuteUserHandler)
+				usersID.GET("/followers", app.handler.ListFollowersHandler)
+				usersID.GET("/following", app.handler.ListFollowingHandler)
 			}
 		}
+		tags := api.Group("/tags")
+		{
+			tags.Use(app.handler.AuthTokenMiddleware)
+			tags.GET("/trending", app.handler.GetTrendingTagsHandler)
+			tags.GET("/:tag/posts", app.handler.GetTagPostsHandler)
+		}
 		authenticate := api.Group("/authenticate")
 		{
 			authenticate.POST("/user", app.handler.RegisterUserHandler)
+			authenticate.POST("/token", app.handler.CreateTokenHandler)
+			authenticate.PUT("/unlock/:token", app.handler.UnlockAccountHandler)
+			authenticate.POST("/forgot-password", app.handler.ForgotPasswordHandler)
+			authenticate.PUT("/reset-password/:token", app.handler.ResetPasswordHandler)
+			authenticate.POST("/refresh", app.handler.RefreshTokenHandler)
+			authenticate.POST("/logout", app.handler.LogoutHandler)
+		}
+		api.POST("/reports", app.handler.AuthTokenMiddleware, app.handler.CreateReportHandler)
+		moderation := api.Group("/moderation")
+		{
+			moderation.Use(app.handler.AuthTokenMiddleware)
+			moderation.GET("/reports", app.handler.RequirePermission("report:moderate"), app.handler.ListReportsHandler)
+			moderation.PUT("/reports/:reportID/claim", app.handler.RequirePermission("report:moderate"), app.handler.ClaimReportHandler)
+			moderation.PUT("/reports/:reportID/resolve", app.handler.RequirePermission("report:moderate"), app.handler.ResolveReportHandler)
+			moderation.GET("/audit-log", app.handler.RequirePermission("audit_log:read"), app.handler.ListAuditLogHandler)
+		}
+		admin := api.Group("/admin")
+		{
+			admin.Use(app.handler.AuthTokenMiddleware)
+			admin.PUT("/posts/:id/restore", app.handler.RequirePermission("post:restore"), app.handler.RestorePostHandler)
+			admin.PUT("/comments/:id/restore", app.handler.RequirePermission("comment:restore"), app.handler.RestoreCommentHandler)
+			admin.GET("/roles", app.handler.RequirePermission("role:manage"), app.handler.ListRolesHandler)
+			admin.GET("/permissions", app.handler.RequirePermission("role:manage"), app.handler.ListPermissionsHandler)
+			admin.PUT("/roles/:name/permissions", app.handler.RequirePermission("role:manage"), app.handler.SetRolePermissionsHandler)
+			admin.PUT("/users/:id/role", app.handler.RequirePermission("role:manage"), app.handler.AssignRoleHandler)
 		}
 		posts := api.Group("/posts")
 		{
@@ -67,9 +115,23 @@ func (app *application) mount() *gin.Engine {
 			{
 				postsID.Use(app.handler.PostsContextMiddleware)
 				postsID.GET("/", app.handler.GetPostHandler)
-				postsID.PATCH("/", app.handler.CheckPostOwnership("moderator", app.handler.UpdatePostHandler))
-				postsID.DELETE("/", app.handler.CheckPostOwnership("admin", app.handler.DeletePostHandler))
-
+				postsID.PATCH("/", app.handler.CheckPostOwnership("post:update:any", app.handler.UpdatePostHandler))
+				postsID.DELETE("/", app.handler.CheckPostOwnership("post:delete:any", app.handler.DeletePostHandler))
+				postsID.GET("/revisions", app.handler.ListPostRevisionsHandler)
+				postsID.PUT("/reactions", app.handler.SetReactionHandler)
+				postsID.DELETE("/reactions", app.handler.DeleteReactionHandler)
+				comments := postsID.Group("/comments")
+				{
+					comments.GET("/", app.handler.ListCommentsHandler)
+					comments.POST("/", app.handler.CreateCommentHandler)
+					commentsID := comments.Group("/:commentID")
+					{
+						commentsID.Use(app.handler.CommentsContextMiddleware)
+						commentsID.GET("/replies", app.handler.ListRepliesHandler)
+						commentsID.PATCH("/", app.handler.CheckCommentOwnership("comment:update:any", app.handler.UpdateCommentHandler))
+						commentsID.DELETE("/", app.handler.CheckCommentOwnership("comment:delete:any", app.handler.DeleteCommentHandler))
+					}
+				}
 			}
 		}
 	}
@@ -77,6 +139,24 @@ func (app *application) mount() *gin.Engine {
 	return r
 }
 
+// checkRateLimitRoutes makes sure every route with its own rate limit policy
+// exists, so a renamed route does not silently fall back to the default
+// policy.
+func (app *application) checkRateLimitRoutes(r *gin.Engine) error {
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for route := range app.config.RateLimiter.Routes {
+		if !registered[route] {
+			return fmt.Errorf("rate limit policy for unknown route %q", route)
+		}
+	}
+
+	return nil
+}
+
 func (app *application) run(mux http.Handler) error {
 	srv := &http.Server{
 		Addr:         app.config.Addr,
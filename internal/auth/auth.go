@@ -5,4 +5,10 @@ import "github.com/golang-jwt/jwt/v5"
 type Authenticator interface {
 	GenerateToken(claims jwt.Claims) (string, error)
 	ValidateToken(token string) (*jwt.Token, error)
-}
\ No newline at end of file
+}
+
+// KeyPublisher is implemented by authenticators whose verification keys can be
+// shared publicly as a JWKS.
+type KeyPublisher interface {
+	PublicKeys() JWKSet
+}
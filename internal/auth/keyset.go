@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keySetFile is the on-disk manifest of a keyset. Key paths are resolved
+// relative to the manifest.
+//
+//	{
+//		"active": "2025-09",
+//		"keys": [
+//			{"kid": "2025-09", "alg": "EdDSA", "path": "2025-09.pem"},
+//			{"kid": "2025-06", "alg": "RS256", "path": "2025-06.pub.pem"},
+//			{"kid": "2025-03", "alg": "RS256", "path": "2025-03.pub.pem", "retired": true}
+//		]
+//	}
+type keySetFile struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		ID      string `json:"kid"`
+		Alg     string `json:"alg"`
+		Path    string `json:"path"`
+		Retired bool   `json:"retired"`
+	} `json:"keys"`
+}
+
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+	retired bool
+}
+
+// KeySetAuthenticator signs tokens with the active key of an RS256/EdDSA
+// keyset and accepts tokens signed by any key that has not been retired.
+type KeySetAuthenticator struct {
+	mu     sync.RWMutex
+	path   string
+	active *signingKey
+	keys   map[string]*signingKey
+	aud    string
+	issuer string
+}
+
+func NewKeySetAuthenticator(path, aud, issuer string) (*KeySetAuthenticator, error) {
+	k := &KeySetAuthenticator{
+		path:   path,
+		aud:    aud,
+		issuer: issuer,
+	}
+
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Reload re-reads the keyset from disk. The previous keyset stays in use if
+// the new one cannot be loaded.
+func (k *KeySetAuthenticator) Reload() error {
+	active, keys, err := loadKeySet(k.path)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.active = active
+	k.keys = keys
+	k.mu.Unlock()
+
+	return nil
+}
+
+func (k *KeySetAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	active := k.active
+	k.mu.RUnlock()
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.id
+
+	tokenString, err := token.SignedString(active.private)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+func (k *KeySetAuthenticator) ValidateToken(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		k.mu.RLock()
+		key, ok := k.keys[kid]
+		k.mu.RUnlock()
+
+		if !ok || key.retired {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+
+		return key.public, nil
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(k.aud),
+		jwt.WithIssuer(k.issuer),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name, jwt.SigningMethodEdDSA.Alg()}),
+	)
+}
+
+// PublicKeys returns the JWKS of every key that has not been retired, sorted
+// by kid so the response is stable.
+func (k *KeySetAuthenticator) PublicKeys() JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		if key.retired {
+			continue
+		}
+		set.Keys = append(set.Keys, newJWK(key))
+	}
+	slices.SortFunc(set.Keys, func(a, b JWK) int {
+		return strings.Compare(a.Kid, b.Kid)
+	})
+
+	return set
+}
+
+func loadKeySet(path string) (*signingKey, map[string]*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var file keySetFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("parsing keyset %s: %w", path, err)
+	}
+
+	keys := make(map[string]*signingKey, len(file.Keys))
+	for _, entry := range file.Keys {
+		if entry.ID == "" {
+			return nil, nil, errors.New("keyset entry is missing a kid")
+		}
+		if _, ok := keys[entry.ID]; ok {
+			return nil, nil, fmt.Errorf("duplicate kid %q in keyset", entry.ID)
+		}
+
+		keyPath := entry.Path
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(filepath.Dir(path), keyPath)
+		}
+
+		key, err := loadKey(keyPath, entry.Alg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading key %q: %w", entry.ID, err)
+		}
+		key.id = entry.ID
+		key.retired = entry.Retired
+
+		keys[entry.ID] = key
+	}
+
+	active, ok := keys[file.Active]
+	if !ok {
+		return nil, nil, fmt.Errorf("active key %q is not in the keyset", file.Active)
+	}
+	if active.retired || active.private == nil {
+		return nil, nil, fmt.Errorf("active key %q must be a non-retired private key", file.Active)
+	}
+
+	return active, keys, nil
+}
+
+func loadKey(path, alg string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{}
+	switch parsed := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.private, key.public = parsed, &parsed.PublicKey
+	case *rsa.PublicKey:
+		key.public = parsed
+	case ed25519.PrivateKey:
+		key.private, key.public = parsed, parsed.Public()
+	case ed25519.PublicKey:
+		key.public = parsed
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		if _, ok := key.public.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("alg %s requires an RSA key", alg)
+		}
+		key.method = jwt.SigningMethodRS256
+	case jwt.SigningMethodEdDSA.Alg():
+		if _, ok := key.public.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("alg %s requires an Ed25519 key", alg)
+		}
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", alg)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testAud    = "test-aud"
+	testIssuer = "test-issuer"
+)
+
+type testKey struct {
+	ID      string `json:"kid"`
+	Alg     string `json:"alg"`
+	Path    string `json:"path"`
+	Retired bool   `json:"retired,omitempty"`
+}
+
+// writeKeySet writes a keyset manifest with the given keys to dir. The PEM
+// files are expected to exist already.
+func writeKeySet(t *testing.T, dir, active string, keys ...testKey) string {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"active": active, "keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "keyset.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeEd25519Key(t *testing.T, dir, name string) {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, name, "PRIVATE KEY", der)
+}
+
+func writeRSAKey(t *testing.T, dir, name string) {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, name, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(priv))
+}
+
+func writeRSAPublicKey(t *testing.T, dir, name string) {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, name, "PUBLIC KEY", der)
+}
+
+func testClaims() jwt.Claims {
+	return jwt.MapClaims{
+		"sub": "user",
+		"aud": testAud,
+		"iss": testIssuer,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func tokenKid(t *testing.T, k *KeySetAuthenticator) string {
+	t.Helper()
+
+	tokenString, err := k.GenerateToken(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := k.ValidateToken(tokenString)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+func TestKeySetSignsWithActiveKey(t *testing.T) {
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "ed.pem")
+	writeRSAKey(t, dir, "rsa.pem")
+
+	for _, active := range []string{"ed", "rsa"} {
+		path := writeKeySet(t, dir, active,
+			testKey{ID: "ed", Alg: "EdDSA", Path: "ed.pem"},
+			testKey{ID: "rsa", Alg: "RS256", Path: "rsa.pem"},
+		)
+
+		k, err := NewKeySetAuthenticator(path, testAud, testIssuer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kid := tokenKid(t, k); kid != active {
+			t.Errorf("token signed with %q, want the active key %q", kid, active)
+		}
+	}
+}
+
+func TestKeySetRejectsRetiredAndUnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "old.pem")
+	writeEd25519Key(t, dir, "new.pem")
+
+	path := writeKeySet(t, dir, "old",
+		testKey{ID: "old", Alg: "EdDSA", Path: "old.pem"},
+		testKey{ID: "new", Alg: "EdDSA", Path: "new.pem"},
+	)
+	k, err := NewKeySetAuthenticator(path, testAud, testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := k.GenerateToken(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeKeySet(t, dir, "new",
+		testKey{ID: "old", Alg: "EdDSA", Path: "old.pem", Retired: true},
+		testKey{ID: "new", Alg: "EdDSA", Path: "new.pem"},
+	)
+	if err := k.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.ValidateToken(oldToken); err == nil {
+		t.Error("token signed by a retired key was accepted")
+	}
+
+	writeKeySet(t, dir, "new", testKey{ID: "new", Alg: "EdDSA", Path: "new.pem"})
+	if err := k.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.ValidateToken(oldToken); err == nil {
+		t.Error("token signed by a key missing from the keyset was accepted")
+	}
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "ed.pem")
+	writeRSAPublicKey(t, dir, "rsa.pub.pem")
+
+	tests := []struct {
+		name   string
+		active string
+		keys   []testKey
+		want   string
+	}{
+		{
+			name:   "missing kid",
+			active: "ed",
+			keys:   []testKey{{Alg: "EdDSA", Path: "ed.pem"}},
+			want:   "missing a kid",
+		},
+		{
+			name:   "duplicate kid",
+			active: "ed",
+			keys:   []testKey{{ID: "ed", Alg: "EdDSA", Path: "ed.pem"}, {ID: "ed", Alg: "EdDSA", Path: "ed.pem"}},
+			want:   "duplicate kid",
+		},
+		{
+			name:   "active key not in keyset",
+			active: "other",
+			keys:   []testKey{{ID: "ed", Alg: "EdDSA", Path: "ed.pem"}},
+			want:   "not in the keyset",
+		},
+		{
+			name:   "active key is public only",
+			active: "rsa",
+			keys:   []testKey{{ID: "rsa", Alg: "RS256", Path: "rsa.pub.pem"}},
+			want:   "non-retired private key",
+		},
+		{
+			name:   "active key is retired",
+			active: "ed",
+			keys:   []testKey{{ID: "ed", Alg: "EdDSA", Path: "ed.pem", Retired: true}},
+			want:   "non-retired private key",
+		},
+		{
+			name:   "alg does not match key",
+			active: "ed",
+			keys:   []testKey{{ID: "ed", Alg: "RS256", Path: "ed.pem"}},
+			want:   "requires an RSA key",
+		},
+		{
+			name:   "missing key file",
+			active: "ed",
+			keys:   []testKey{{ID: "ed", Alg: "EdDSA", Path: "missing.pem"}},
+			want:   "loading key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeKeySet(t, dir, tt.active, tt.keys...)
+			_, err := NewKeySetAuthenticator(path, testAud, testIssuer)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewKeySetAuthenticator() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeySetReloadKeepsPreviousKeysOnError(t *testing.T) {
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "ed.pem")
+
+	path := writeKeySet(t, dir, "ed", testKey{ID: "ed", Alg: "EdDSA", Path: "ed.pem"})
+	k, err := NewKeySetAuthenticator(path, testAud, testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Reload(); err == nil {
+		t.Fatal("Reload of a broken keyset succeeded")
+	}
+	if kid := tokenKid(t, k); kid != "ed" {
+		t.Errorf("token signed with %q after a failed reload, want %q", kid, "ed")
+	}
+}
+
+func TestPublicKeysSortedByKid(t *testing.T) {
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "ed.pem")
+	writeRSAPublicKey(t, dir, "rsa.pub.pem")
+
+	path := writeKeySet(t, dir, "b",
+		testKey{ID: "d", Alg: "EdDSA", Path: "ed.pem"},
+		testKey{ID: "b", Alg: "EdDSA", Path: "ed.pem"},
+		testKey{ID: "c", Alg: "RS256", Path: "rsa.pub.pem", Retired: true},
+		testKey{ID: "a", Alg: "RS256", Path: "rsa.pub.pem"},
+		testKey{ID: "e", Alg: "EdDSA", Path: "ed.pem"},
+	)
+	k, err := NewKeySetAuthenticator(path, testAud, testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 10 {
+		var kids []string
+		for _, jwk := range k.PublicKeys().Keys {
+			kids = append(kids, jwk.Kid)
+		}
+		if got := strings.Join(kids, ","); got != "a,b,d,e" {
+			t.Fatalf("PublicKeys() kids = %s, want a,b,d,e", got)
+		}
+	}
+}
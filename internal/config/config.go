@@ -1,6 +1,9 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"maps"
 	"time"
 
 	"github.com/cprakhar/gopher-social/internal/env"
@@ -18,6 +21,12 @@ type Config struct {
 	Auth        authConfig
 	Redis       redisConfig
 	RateLimiter ratelimiter.Config
+	Timeline    TimelineConfig
+	SoftDelete  SoftDeleteConfig
+	Roles       RolesConfig
+	Tags        TagsConfig
+	// CursorSecret signs the opaque pagination cursors handed to clients.
+	CursorSecret string
 }
 
 type redisConfig struct {
@@ -30,6 +39,19 @@ type redisConfig struct {
 type authConfig struct {
 	Basic basicConfig
 	Token tokenConfig
+	Login loginConfig
+	// PasswordResetExp is how long a password reset link stays valid.
+	PasswordResetExp time.Duration
+}
+
+// loginConfig locks out accounts and IP addresses with too many failed
+// logins. Failures are tracked in Redis when it is enabled.
+type loginConfig struct {
+	Account ratelimiter.LockoutPolicy
+	IP      ratelimiter.LockoutPolicy
+	// MaxTracked bounds how many accounts and addresses are tracked in
+	// memory when Redis is disabled.
+	MaxTracked int
 }
 
 type basicConfig struct {
@@ -38,10 +60,58 @@ type basicConfig struct {
 }
 
 type tokenConfig struct {
-	Secret string
-	Exp    time.Duration
-	Iss    string
-	Aud    string
+	Secret     string
+	KeySet     string
+	Exp        time.Duration
+	RefreshExp time.Duration
+	Iss        string
+	Aud        string
+}
+
+// TimelineConfig tunes the Redis-backed feed timelines, which are only used
+// when Redis is enabled.
+type TimelineConfig struct {
+	// MaxLength is the number of post IDs kept per timeline.
+	MaxLength int
+	// FanoutLimit is the follower count above which an author's posts are
+	// pulled at read time instead of pushed to every follower.
+	FanoutLimit int
+}
+
+// SoftDeleteConfig controls how long deleted posts and comments stay
+// restorable before the purge job removes them.
+type SoftDeleteConfig struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// RolesConfig controls how the in-process role registry is kept in sync.
+// Changes are picked up immediately through LISTEN/NOTIFY; RefreshInterval
+// is a fallback in case a notification is missed.
+type RolesConfig struct {
+	RefreshInterval time.Duration
+}
+
+// TagsConfig controls how long hourly tag counts are kept around. Counts
+// older than the longest trending window are pruned every PruneInterval.
+type TagsConfig struct {
+	PruneInterval time.Duration
+}
+
+// rateLimitRoutes holds the routes that need a tighter or looser limit than
+// the default, keyed by method and route pattern. RATELIMITER_ROUTES can
+// change these limits or add routes.
+var rateLimitRoutes = map[string]ratelimiter.Policy{
+	"POST /v1/authenticate/user":                 {Name: "register", Limit: 5, Window: time.Hour},
+	"POST /v1/authenticate/token":                {Name: "login", Limit: 10, Window: time.Minute},
+	"POST /v1/authenticate/refresh":              {Name: "refresh", Limit: 30, Window: time.Minute},
+	"POST /v1/authenticate/forgot-password":      {Name: "forgot-password", Limit: 5, Window: time.Hour},
+	"PUT /v1/authenticate/reset-password/:token": {Name: "reset-password", Limit: 10, Window: time.Hour},
+	"GET /v1/users/feed/":                        {Name: "feed", Limit: 60, Window: time.Minute},
+	"GET /v1/search":                             {Name: "search", Limit: 30, Window: time.Minute},
+	"POST /v1/posts/":                            {Name: "create-post", Limit: 10, Window: time.Minute},
+	"POST /v1/posts/:id/comments/":               {Name: "create-comment", Limit: 30, Window: time.Minute},
+	"POST /v1/reports":                           {Name: "report", Limit: 20, Window: time.Hour},
 }
 
 type MailConfig struct {
@@ -58,7 +128,12 @@ type DBConfig struct {
 	MaxConnLifetime time.Duration
 }
 
-func Load() Config {
+func Load() (Config, error) {
+	routes, err := loadRateLimitRoutes(env.GetString("RATELIMITER_ROUTES", ""))
+	if err != nil {
+		return Config{}, err
+	}
+
 	cfg := Config{
 		Addr:   env.GetString("ADDR", ":8080"),
 		ApiURL: env.GetString("EXTERNAL_URL", "localhost:8080"),
@@ -70,7 +145,7 @@ func Load() Config {
 			MaxIdleTime:     env.GetDuration("DB_MAX_IDLE_TIME", 15*time.Minute),
 			MaxConnLifetime: env.GetDuration("DB_MAX_CONN_LIFETIME", time.Hour),
 		},
-		Env:     env.GetString("ENV", "development"),
+		Env: env.GetString("ENV", "development"),
 		Mail: MailConfig{
 			Exp:    env.GetDuration("MAIL_EXP", 3*24*time.Hour),
 			ApiKey: env.GetString("MAIL_API_KEY", ""),
@@ -82,11 +157,31 @@ func Load() Config {
 				Password: env.GetString("BASIC_AUTH_PASSWORD", "adminpassword"),
 			},
 			Token: tokenConfig{
-				Secret: env.GetString("AUTH_TOKEN", ""),
-				Exp:    env.GetDuration("AUTH_TOKEN_EXP", 3*24*time.Hour),
-				Iss:    env.GetString("AUTH_TOKEN_ISS", "gopher-social"),
-				Aud:    env.GetString("AUTH_TOKEN_AUD", "gopher-social"),
+				Secret:     env.GetString("AUTH_TOKEN", ""),
+				KeySet:     env.GetString("AUTH_TOKEN_KEYSET", ""),
+				Exp:        env.GetDuration("AUTH_TOKEN_EXP", 15*time.Minute),
+				RefreshExp: env.GetDuration("AUTH_REFRESH_TOKEN_EXP", 30*24*time.Hour),
+				Iss:        env.GetString("AUTH_TOKEN_ISS", "gopher-social"),
+				Aud:        env.GetString("AUTH_TOKEN_AUD", "gopher-social"),
 			},
+			Login: loginConfig{
+				Account: ratelimiter.LockoutPolicy{
+					Name:      "account",
+					Threshold: env.GetInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+					BaseDelay: env.GetDuration("LOGIN_LOCKOUT_BASE_DELAY", time.Minute),
+					MaxDelay:  env.GetDuration("LOGIN_LOCKOUT_MAX_DELAY", time.Hour),
+					Window:    env.GetDuration("LOGIN_LOCKOUT_WINDOW", time.Hour),
+				},
+				IP: ratelimiter.LockoutPolicy{
+					Name:      "ip",
+					Threshold: env.GetInt("LOGIN_LOCKOUT_IP_THRESHOLD", 50),
+					BaseDelay: env.GetDuration("LOGIN_LOCKOUT_BASE_DELAY", time.Minute),
+					MaxDelay:  env.GetDuration("LOGIN_LOCKOUT_MAX_DELAY", time.Hour),
+					Window:    env.GetDuration("LOGIN_LOCKOUT_WINDOW", time.Hour),
+				},
+				MaxTracked: env.GetInt("LOGIN_LOCKOUT_MAX_TRACKED", 100000),
+			},
+			PasswordResetExp: env.GetDuration("PASSWORD_RESET_EXP", time.Hour),
 		},
 		Redis: redisConfig{
 			Addr:     env.GetString("REDIS_ADDR", "localhost:6379"),
@@ -94,11 +189,71 @@ func Load() Config {
 			DB:       env.GetInt("REDIS_DB", 0),
 			Enabled:  env.GetBool("REDIS_ENABLED", false),
 		},
+		Timeline: TimelineConfig{
+			MaxLength:   env.GetInt("TIMELINE_MAX_LENGTH", 800),
+			FanoutLimit: env.GetInt("TIMELINE_FANOUT_LIMIT", 10000),
+		},
+		SoftDelete: SoftDeleteConfig{
+			Retention:     env.GetDuration("SOFT_DELETE_RETENTION", 30*24*time.Hour),
+			PurgeInterval: env.GetDuration("SOFT_DELETE_PURGE_INTERVAL", time.Hour),
+		},
+		Roles: RolesConfig{
+			RefreshInterval: env.GetDuration("ROLES_REFRESH_INTERVAL", 5*time.Minute),
+		},
+		Tags: TagsConfig{
+			PruneInterval: env.GetDuration("TAGS_PRUNE_INTERVAL", time.Hour),
+		},
+		CursorSecret: env.GetString("CURSOR_SECRET", ""),
 		RateLimiter: ratelimiter.Config{
-			RequestsPerTimeFrame: env.GetInt("RATELIMITER_REQUESTS_COUNT", 20),
-			TimeFrame:            time.Second * 5,
-			Enabled:              env.GetBool("RATELIMITER_ENABLED", true),
+			Enabled:    env.GetBool("RATELIMITER_ENABLED", true),
+			Backend:    env.GetString("RATELIMITER_BACKEND", "memory"),
+			Algorithm:  env.GetString("RATELIMITER_ALGORITHM", ratelimiter.FixedWindow),
+			MaxClients: env.GetInt("RATELIMITER_MAX_CLIENTS", 100000),
+			Default: ratelimiter.Policy{
+				Name:   "default",
+				Limit:  env.GetInt("RATELIMITER_REQUESTS_COUNT", 20),
+				Window: env.GetDuration("RATELIMITER_TIMEFRAME", 5*time.Second),
+			},
+			Routes: routes,
 		},
 	}
-	return cfg
+	return cfg, nil
+}
+
+// loadRateLimitRoutes applies the route policies in spec on top of the
+// built-in ones. Overridden routes keep their policy name, so clients are
+// still counted under the same key.
+func loadRateLimitRoutes(spec string) (map[string]ratelimiter.Policy, error) {
+	overrides, err := ratelimiter.ParseRoutes(spec)
+	if err != nil {
+		return nil, fmt.Errorf("RATELIMITER_ROUTES: %w", err)
+	}
+
+	routes := maps.Clone(rateLimitRoutes)
+	for route, p := range overrides {
+		if builtin, ok := routes[route]; ok {
+			p.Name = builtin.Name
+		}
+		routes[route] = p
+	}
+
+	return routes, nil
+}
+
+// Validate reports settings that would make the background jobs fail at
+// runtime.
+func (c Config) Validate() error {
+	if c.SoftDelete.PurgeInterval <= 0 {
+		return errors.New("SOFT_DELETE_PURGE_INTERVAL must be positive")
+	}
+	if c.SoftDelete.Retention < 0 {
+		return errors.New("SOFT_DELETE_RETENTION must not be negative")
+	}
+	if c.Roles.RefreshInterval <= 0 {
+		return errors.New("ROLES_REFRESH_INTERVAL must be positive")
+	}
+	if c.Tags.PruneInterval <= 0 {
+		return errors.New("TAGS_PRUNE_INTERVAL must be positive")
+	}
+	return nil
 }
@@ -0,0 +1,118 @@
+// Package diff computes line-based differences between two texts.
+package diff
+
+import "strings"
+
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// maxCells bounds the size of the LCS table, so a diff never allocates more
+// than about half a megabyte. Changed regions with more line pairs are
+// reported as fully replaced instead.
+const maxCells = 1 << 16
+
+// Op is a single line of a diff.
+type Op struct {
+	Kind string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Lines returns the operations that turn a into b, one per line, using the
+// longest common subsequence of their lines.
+func Lines(a, b string) []Op {
+	if a == b {
+		return []Op{}
+	}
+
+	x, y := split(a), split(b)
+
+	// lines shared at both ends are kept as they are, so edits to long texts
+	// only need a table for the part that changed
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Op, 0, max(len(x), len(y)))
+	for _, line := range x[:prefix] {
+		ops = append(ops, Op{Kind: Equal, Text: line})
+	}
+	ops = append(ops, middle(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, line := range x[len(x)-suffix:] {
+		ops = append(ops, Op{Kind: Equal, Text: line})
+	}
+
+	return ops
+}
+
+// middle diffs the lines between the common prefix and suffix.
+func middle(x, y []string) []Op {
+	if len(x)*len(y) > maxCells {
+		return replace(x, y)
+	}
+
+	// lcs[i][j] is the length of the LCS of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]Op, 0, max(len(x), len(y)))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			ops = append(ops, Op{Kind: Equal, Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, Op{Kind: Delete, Text: x[i]})
+			i++
+		default:
+			ops = append(ops, Op{Kind: Insert, Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		ops = append(ops, Op{Kind: Delete, Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		ops = append(ops, Op{Kind: Insert, Text: y[j]})
+	}
+
+	return ops
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func replace(x, y []string) []Op {
+	ops := make([]Op, 0, len(x)+len(y))
+	for _, line := range x {
+		ops = append(ops, Op{Kind: Delete, Text: line})
+	}
+	for _, line := range y {
+		ops = append(ops, Op{Kind: Insert, Text: line})
+	}
+	return ops
+}
@@ -0,0 +1,122 @@
+package diff
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func eq(text string) Op  { return Op{Kind: Equal, Text: text} }
+func ins(text string) Op { return Op{Kind: Insert, Text: text} }
+func del(text string) Op { return Op{Kind: Delete, Text: text} }
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Op
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\nc",
+			b:    "a\nb\nc",
+			want: []Op{},
+		},
+		{
+			name: "insert only",
+			a:    "a\nc",
+			b:    "a\nb\nc\nd",
+			want: []Op{eq("a"), ins("b"), eq("c"), ins("d")},
+		},
+		{
+			name: "insert into empty",
+			a:    "",
+			b:    "a\nb",
+			want: []Op{ins("a"), ins("b")},
+		},
+		{
+			name: "delete only",
+			a:    "a\nb\nc\nd",
+			b:    "b\nd",
+			want: []Op{del("a"), eq("b"), del("c"), eq("d")},
+		},
+		{
+			name: "delete everything",
+			a:    "a\nb",
+			b:    "",
+			want: []Op{del("a"), del("b")},
+		},
+		{
+			name: "mixed",
+			a:    "title\nold line\nkept\nremoved\nend",
+			b:    "title\nnew line\nkept\nend\nadded",
+			want: []Op{eq("title"), del("old line"), ins("new line"), eq("kept"), del("removed"), eq("end"), ins("added")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.a, tt.b)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Lines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinesOverCapReplacesChangedLines(t *testing.T) {
+	// two unrelated blocks whose LCS table would exceed maxCells, between a
+	// shared first and last line
+	var x, y []string
+	for i := range 300 {
+		x = append(x, "old "+strconv.Itoa(i))
+		y = append(y, "new "+strconv.Itoa(i))
+	}
+	if len(x)*len(y) <= maxCells {
+		t.Fatalf("test texts of %d cells do not exceed maxCells", len(x)*len(y))
+	}
+
+	a := "first\n" + strings.Join(x, "\n") + "\nlast"
+	b := "first\n" + strings.Join(y, "\n") + "\nlast"
+
+	want := []Op{eq("first")}
+	for _, line := range x {
+		want = append(want, del(line))
+	}
+	for _, line := range y {
+		want = append(want, ins(line))
+	}
+	want = append(want, eq("last"))
+
+	if got := Lines(a, b); !slices.Equal(got, want) {
+		t.Errorf("Lines() returned %d ops, want the %d ops of a whole replace", len(got), len(want))
+	}
+}
+
+func TestLinesSmallEditToLongText(t *testing.T) {
+	// far over maxCells as a whole, but only one line changed
+	lines := make([]string, 2000)
+	for i := range lines {
+		lines[i] = "line " + strconv.Itoa(i)
+	}
+	a := strings.Join(lines, "\n")
+	lines[1000] = "edited"
+	b := strings.Join(lines, "\n")
+
+	got := Lines(a, b)
+
+	var changed []Op
+	for _, op := range got {
+		if op.Kind != Equal {
+			changed = append(changed, op)
+		}
+	}
+	want := []Op{del("line 1000"), ins("edited")}
+	if !slices.Equal(changed, want) {
+		t.Errorf("changed ops = %v, want %v", changed, want)
+	}
+	if len(got) != 2001 {
+		t.Errorf("Lines() returned %d ops, want 2001", len(got))
+	}
+}
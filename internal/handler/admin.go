@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// RestorePost godoc
+//
+//	@Summary	restore a post
+//	@Schemes
+//	@Description	restore a deleted post that has not been purged yet
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"post id"
+//	@Success		204	"No Content"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/admin/posts/{id}/restore [put]
+func (h *Handler) RestorePostHandler(ctx *gin.Context) {
+	admin := userFromCtx(ctx)
+	id := ctx.Param("id")
+
+	if err := validator.New().Var(id, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := h.Store.Posts.Restore(ctx, id, admin.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RestoreComment godoc
+//
+//	@Summary	restore a comment
+//	@Schemes
+//	@Description	restore a deleted comment that has not been purged yet
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"comment id"
+//	@Success		204	"No Content"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/admin/comments/{id}/restore [put]
+func (h *Handler) RestoreCommentHandler(ctx *gin.Context) {
+	admin := userFromCtx(ctx)
+	id := ctx.Param("id")
+
+	if err := validator.New().Var(id, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := h.Store.Comments.Restore(ctx, id, admin.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
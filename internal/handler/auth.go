@@ -1,12 +1,18 @@
 package handler
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cprakhar/gopher-social/internal/auth"
 	"github.com/cprakhar/gopher-social/internal/mail"
+	"github.com/cprakhar/gopher-social/internal/ratelimiter"
 	"github.com/cprakhar/gopher-social/internal/store"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -29,6 +35,7 @@ type CreateUserPayload struct {
 //	@Produce		json
 //	@Param			payload	body		CreateUserPayload	true	"user payload"
 //	@Success		201		{object}	map[string]any
+//	@Failure		409		{object}	map[string]string
 //	@Failure		500		{object}	map[string]string
 //	@Router			/users [post]
 func (h *Handler) RegisterUserHandler(ctx *gin.Context) {
@@ -53,7 +60,12 @@ func (h *Handler) RegisterUserHandler(ctx *gin.Context) {
 	hashToken := hex.EncodeToString(hash[:])
 
 	if err := h.Store.Users.CreateAndInvite(ctx, user, hashToken, h.Cfg.Mail.Exp); err != nil {
-		h.internalServerErr(ctx, err)
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			h.conflictErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
 		return
 	}
 
@@ -121,20 +133,29 @@ type CreateUserTokenPayload struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type TokenResponse struct {
+	Token            string    `json:"token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
 // CreateToken godoc
 //
 //	@Summary	create a JWT token
 //	@Schemes
-//	@Description	create a JWT token using email and password
+//	@Description	create a JWT access token and a refresh token using email and password
 //	@Tags			auth
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CreateUserTokenPayload	true	"credentials payload"
-//	@Success		201		{object}	string
+//	@Success		201		{object}	TokenResponse
 //	@Failure		400		{object}	map[string]string
 //	@Failure		401		{object}	map[string]string
+//	@Failure		429		{object}	map[string]string
 //	@Failure		500		{object}	map[string]string
-//	@Router			/tokens [post]
+//	@Router			/authenticate/token [post]
 func (h *Handler) CreateTokenHandler(ctx *gin.Context) {
 	// parse credentials payload
 	var payload CreateUserTokenPayload
@@ -143,11 +164,365 @@ func (h *Handler) CreateTokenHandler(ctx *gin.Context) {
 		return
 	}
 
+	// failures are tracked per email whether or not an account exists, so a
+	// lockout does not reveal which emails are registered
+	accountKey := strings.ToLower(payload.Email)
+	ipKey := ctx.ClientIP()
+
+	if locked := h.loginLockedFor(ctx, accountKey, ipKey); locked > 0 {
+		h.tooManyRequestsErr(ctx, seconds(locked))
+		return
+	}
+
 	// fetch the user (check if user exists) from the payload
 	user, err := h.Store.Users.GetByEmail(ctx, payload.Email)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	// verify the password against the stored hash; unknown emails are
+	// checked against a dummy hash so they take as long to reject
+	if user == nil {
+		err = timingUser().Password.Compare(payload.Password)
+		if err == nil {
+			err = store.ErrNotFound
+		}
+	} else {
+		err = user.Password.Compare(payload.Password)
+	}
 	if err != nil {
-		switch err {
-		case store.ErrNotFound:
+		h.recordLoginFailure(ctx, accountKey, ipKey, user)
+		h.unauthorizedErr(ctx, err)
+		return
+	}
+
+	if err := h.AccountLockout.Reset(ctx, accountKey); err != nil {
+		h.Logger.Errorw("error resetting login failures", "error", err)
+	}
+
+	if user.IsSuspended() {
+		h.forbiddenErr(ctx)
+		return
+	}
+
+	// start a new refresh token family for this login
+	refreshToken := &store.RefreshToken{
+		Token:         uuid.NewString(),
+		UserID:        user.ID,
+		FamilyID:      uuid.NewString(),
+		AccessTokenID: uuid.NewString(),
+		ExpiresAt:     time.Now().Add(h.Cfg.Auth.Token.RefreshExp),
+	}
+
+	if err := h.Store.RefreshTokens.Create(ctx, refreshToken); err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	resp, err := h.newTokenResponse(refreshToken)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	// send it to the client
+	writeJSON(ctx, http.StatusCreated, resp)
+}
+
+// timingUser has a real password hash to compare against when no account
+// matches the email.
+var timingUser = sync.OnceValue(func() *store.User {
+	user := &store.User{}
+	if err := user.Password.Set(uuid.NewString()); err != nil {
+		panic(err)
+	}
+	return user
+})
+
+// loginLockedFor returns how long logins for the account or from the IP
+// address are locked out. Lockout errors are logged and ignored, so an
+// unavailable backend does not stop everyone from logging in.
+func (h *Handler) loginLockedFor(ctx *gin.Context, accountKey, ipKey string) time.Duration {
+	accountLocked, err := h.AccountLockout.Check(ctx, accountKey)
+	if err != nil {
+		h.Logger.Errorw("error checking account lockout", "error", err)
+	}
+
+	ipLocked, err := h.IPLockout.Check(ctx, ipKey)
+	if err != nil {
+		h.Logger.Errorw("error checking ip lockout", "error", err)
+	}
+
+	return max(accountLocked, ipLocked)
+}
+
+// recordLoginFailure counts a failed login against the account and the IP
+// address. When the account first gets locked out, its owner is emailed a
+// link to unlock it.
+func (h *Handler) recordLoginFailure(ctx *gin.Context, accountKey, ipKey string, user *store.User) {
+	if _, _, err := h.IPLockout.Fail(ctx, ipKey); err != nil {
+		h.Logger.Errorw("error recording ip login failure", "error", err)
+	}
+
+	failures, locked, err := h.AccountLockout.Fail(ctx, accountKey)
+	if err != nil {
+		h.Logger.Errorw("error recording account login failure", "error", err)
+		return
+	}
+
+	if user == nil || locked == 0 || failures != h.Cfg.Auth.Login.Account.Threshold {
+		return
+	}
+
+	// sent in the background so the response takes as long as for an
+	// unknown email
+	go h.sendUnlockEmail(accountKey, user)
+}
+
+func (h *Handler) sendUnlockEmail(accountKey string, user *store.User) {
+	token, err := h.AccountLockout.IssueUnlock(context.Background(), accountKey)
+	if err != nil {
+		h.Logger.Errorw("error issuing unlock token", "user_id", user.ID, "error", err)
+		return
+	}
+
+	isProdEnv := h.Cfg.Env == "production"
+
+	vars := struct {
+		Username  string
+		UnlockURL string
+	}{
+		Username:  user.Username,
+		UnlockURL: h.Cfg.WebURL + "/unlock/" + token,
+	}
+	if _, err := h.Mailer.Send(mail.AccountLockedTemplate, user.Username, user.Email, vars, !isProdEnv); err != nil {
+		h.Logger.Errorw("error sending unlock email", "user_id", user.ID, "error", err)
+	}
+}
+
+// UnlockAccount godoc
+//
+//	@Summary	unlock an account
+//	@Schemes
+//	@Description	lift a login lockout using the token sent via email
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	path	string	true	"unlock token"
+//	@Success		204		"No Content"
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/authenticate/unlock/{token} [put]
+func (h *Handler) UnlockAccountHandler(ctx *gin.Context) {
+	if err := h.AccountLockout.Unlock(ctx, ctx.Param("token")); err != nil {
+		switch {
+		case errors.Is(err, ratelimiter.ErrUnlockTokenNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordPayload struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ForgotPassword godoc
+//
+//	@Summary	request a password reset
+//	@Schemes
+//	@Description	email a single-use password reset link; the response is the same whether or not the email is registered
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body	ForgotPasswordPayload	true	"email payload"
+//	@Success		202		"Accepted"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/authenticate/forgot-password [post]
+func (h *Handler) ForgotPasswordHandler(ctx *gin.Context) {
+	var payload ForgotPasswordPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	user, err := h.Store.Users.GetByEmail(ctx, payload.Email)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	// sent in the background so the response does not reveal whether the
+	// email is registered
+	if user != nil {
+		go h.sendPasswordResetEmail(user)
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (h *Handler) sendPasswordResetEmail(user *store.User) {
+	token := uuid.NewString()
+	if err := h.Store.Users.CreatePasswordReset(context.Background(), user.ID, token, h.Cfg.Auth.PasswordResetExp); err != nil {
+		h.Logger.Errorw("error creating password reset", "user_id", user.ID, "error", err)
+		return
+	}
+
+	isProdEnv := h.Cfg.Env == "production"
+
+	vars := struct {
+		Username string
+		ResetURL string
+	}{
+		Username: user.Username,
+		ResetURL: h.Cfg.WebURL + "/reset-password/" + token,
+	}
+	if _, err := h.Mailer.Send(mail.PasswordResetTemplate, user.Username, user.Email, vars, !isProdEnv); err != nil {
+		h.Logger.Errorw("error sending password reset email", "user_id", user.ID, "error", err)
+	}
+}
+
+// ResetPassword godoc
+//
+//	@Summary	reset a password
+//	@Schemes
+//	@Description	set a new password using the token sent via email; every session of the user is signed out
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	path	string					true	"password reset token"
+//	@Param			payload	body	ResetPasswordPayload	true	"password payload"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/authenticate/reset-password/{token} [put]
+func (h *Handler) ResetPasswordHandler(ctx *gin.Context) {
+	var payload ResetPasswordPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	user, accessTokenIDs, err := h.Store.Users.ResetPassword(ctx, ctx.Param("token"), payload.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	if err := h.revokeAccessTokens(ctx, accessTokenIDs); err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	// the owner has proven access to the mailbox, so lift any login lockout
+	if err := h.AccountLockout.Reset(ctx, strings.ToLower(user.Email)); err != nil {
+		h.Logger.Errorw("error resetting login failures", "error", err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken godoc
+//
+//	@Summary	refresh a JWT token
+//	@Schemes
+//	@Description	exchange a refresh token for a new access token and refresh token
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshTokenPayload	true	"refresh token payload"
+//	@Success		201		{object}	TokenResponse
+//	@Failure		400		{object}	map[string]string
+//	@Failure		401		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/authenticate/refresh [post]
+func (h *Handler) RefreshTokenHandler(ctx *gin.Context) {
+	var payload RefreshTokenPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	refreshToken := &store.RefreshToken{
+		Token:         uuid.NewString(),
+		AccessTokenID: uuid.NewString(),
+		ExpiresAt:     time.Now().Add(h.Cfg.Auth.Token.RefreshExp),
+	}
+
+	if err := h.Store.RefreshTokens.Rotate(ctx, payload.RefreshToken, refreshToken); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.unauthorizedErr(ctx, err)
+			return
+		case errors.Is(err, store.ErrTokenReused):
+			// a rotated token was presented again, so the family may be stolen
+			h.Logger.Warnw("refresh token reuse detected", "user_id", refreshToken.UserID, "family_id", refreshToken.FamilyID)
+			if err := h.revokeTokenFamily(ctx, refreshToken.FamilyID); err != nil {
+				h.internalServerErr(ctx, err)
+				return
+			}
+			h.unauthorizedErr(ctx, err)
+			return
+		default:
+			h.internalServerErr(ctx, err)
+			return
+		}
+	}
+
+	resp, err := h.newTokenResponse(refreshToken)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	writeJSON(ctx, http.StatusCreated, resp)
+}
+
+// Logout godoc
+//
+//	@Summary	logout
+//	@Schemes
+//	@Description	revoke the refresh token family and every access token issued from it
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body	RefreshTokenPayload	true	"refresh token payload"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		401		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/authenticate/logout [post]
+func (h *Handler) LogoutHandler(ctx *gin.Context) {
+	var payload RefreshTokenPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	refreshToken, err := h.Store.RefreshTokens.GetByToken(ctx, payload.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
 			h.unauthorizedErr(ctx, err)
 			return
 		default:
@@ -155,26 +530,88 @@ func (h *Handler) CreateTokenHandler(ctx *gin.Context) {
 			return
 		}
 	}
-	// generate a token -> add claims
 
+	if err := h.revokeTokenFamily(ctx, refreshToken.FamilyID); err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// JWKS godoc
+//
+//	@Summary	get the JSON web key set
+//	@Schemes
+//	@Description	get the public keys used to verify access tokens
+//	@Tags			auth
+//	@Produce		json
+//	@Success		200	{object}	auth.JWKSet
+//	@Failure		404	{object}	map[string]string
+//	@Router			/.well-known/jwks.json [get]
+func (h *Handler) JWKSHandler(ctx *gin.Context) {
+	publisher, ok := h.Authenticator.(auth.KeyPublisher)
+	if !ok {
+		h.notFoundErr(ctx, errors.New("authenticator does not publish public keys"))
+		return
+	}
+
+	// served without the data envelope so standard JWKS clients can consume it
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, publisher.PublicKeys())
+}
+
+// newTokenResponse signs an access token paired with the given refresh token.
+func (h *Handler) newTokenResponse(refreshToken *store.RefreshToken) (TokenResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(h.Cfg.Auth.Token.Exp)
 	claims := jwt.RegisteredClaims{
 		Issuer:    h.Cfg.Auth.Token.Iss,
-		Subject:   user.ID,
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(h.Cfg.Auth.Token.Exp)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		NotBefore: jwt.NewNumericDate(time.Now()),
+		Subject:   refreshToken.UserID,
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
 		Audience: jwt.ClaimStrings{
 			h.Cfg.Auth.Token.Aud,
 		},
-		ID: uuid.NewString(),
+		ID: refreshToken.AccessTokenID,
 	}
 
 	tokenStr, err := h.Authenticator.GenerateToken(claims)
 	if err != nil {
-		h.internalServerErr(ctx, err)
-		return
+		return TokenResponse{}, err
 	}
 
-	// send it to the client
-	writeJSON(ctx, http.StatusCreated, tokenStr)
+	return TokenResponse{
+		Token:            tokenStr,
+		TokenType:        "Bearer",
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken.Token,
+		RefreshExpiresAt: refreshToken.ExpiresAt,
+	}, nil
+}
+
+// revokeTokenFamily revokes the refresh token family and denylists the access
+// tokens issued from it until they would have expired anyway.
+func (h *Handler) revokeTokenFamily(ctx context.Context, familyID string) error {
+	accessTokenIDs, err := h.Store.RefreshTokens.RevokeFamily(ctx, familyID)
+	if err != nil {
+		return err
+	}
+
+	return h.revokeAccessTokens(ctx, accessTokenIDs)
+}
+
+func (h *Handler) revokeAccessTokens(ctx context.Context, accessTokenIDs []string) error {
+	if !h.Cfg.Redis.Enabled {
+		return nil
+	}
+
+	for _, id := range accessTokenIDs {
+		if err := h.CacheStorage.Tokens.Revoke(ctx, id, h.Cfg.Auth.Token.Exp); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
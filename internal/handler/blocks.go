@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// BlockUser godoc
+//
+//	@Summary	block a user
+//	@Schemes
+//	@Description	block a user by id, removing follows in both directions and hiding each user's content from the other
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"user id to block"
+//	@Success		204	"No Content"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/block [put]
+func (h *Handler) BlockUserHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
+	blockedID := ctx.Param("id")
+
+	if err := validator.New().Var(blockedID, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := h.Store.Blocks.Block(ctx, user.ID, blockedID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrSelfBlock):
+			h.badRequestErr(ctx, err)
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	if h.Cfg.Redis.Enabled {
+		if err := h.pruneTimeline(ctx, user.ID, blockedID); err != nil {
+			h.Logger.Errorw("error pruning timeline", "user_id", user.ID, "following_id", blockedID, "error", err)
+		}
+		if err := h.pruneTimeline(ctx, blockedID, user.ID); err != nil {
+			h.Logger.Errorw("error pruning timeline", "user_id", blockedID, "following_id", user.ID, "error", err)
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// UnblockUser godoc
+//
+//	@Summary	unblock a user
+//	@Schemes
+//	@Description	unblock a user by id; follows removed by the block are not restored
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"user id to unblock"
+//	@Success		204	"No Content"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/block [delete]
+func (h *Handler) UnblockUserHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
+	blockedID := ctx.Param("id")
+
+	if err := validator.New().Var(blockedID, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := h.Store.Blocks.Unblock(ctx, user.ID, blockedID); err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// MuteUser godoc
+//
+//	@Summary	mute a user
+//	@Schemes
+//	@Description	hide a user's posts from the current user's feed without unfollowing them
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"user id to mute"
+//	@Success		204	"No Content"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/mute [put]
+func (h *Handler) MuteUserHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
+	mutedID := ctx.Param("id")
+
+	if err := validator.New().Var(mutedID, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := h.Store.Mutes.Mute(ctx, user.ID, mutedID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrSelfMute):
+			h.badRequestErr(ctx, err)
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// UnmuteUser godoc
+//
+//	@Summary	unmute a user
+//	@Schemes
+//	@Description	show a muted user's posts in the current user's feed again
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"user id to unmute"
+//	@Success		204	"No Content"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/mute [delete]
+func (h *Handler) UnmuteUserHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
+	mutedID := ctx.Param("id")
+
+	if err := validator.New().Var(mutedID, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := h.Store.Mutes.Unmute(ctx, user.ID, mutedID); err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
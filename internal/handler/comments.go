@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type CreateCommentPayload struct {
+	Content  string  `json:"content" binding:"required,max=1000"`
+	ParentID *string `json:"parent_id,omitempty" binding:"omitempty,uuid"`
+}
+
+type UpdateCommentPayload struct {
+	Content string `json:"content" binding:"required,max=1000"`
+}
+
+type CommentsPage struct {
+	Comments   []store.Comment `json:"comments"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// ListComments godoc
+//
+//	@Summary	list comments
+//	@Schemes
+//	@Description	list the top-level comments of a post, newest first, with cursor pagination
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"post id"
+//	@Param			limit	query		int		false	"number of comments to return"	default(20)
+//	@Param			cursor	query		string	false	"cursor returned by the previous page"
+//	@Success		200		{object}	CommentsPage
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments [get]
+func (h *Handler) ListCommentsHandler(ctx *gin.Context) {
+	cq := store.PaginatedCursorQuery{
+		Limit: 20,
+	}
+
+	cq, err := cq.Parse(ctx, h.Cursors)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(cq); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	post := postFromCtx(ctx)
+
+	comments, err := h.Store.Comments.List(ctx, post.ID, cq)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	page := CommentsPage{Comments: comments}
+	if len(comments) == cq.Limit {
+		last := comments[len(comments)-1]
+		page.NextCursor = h.Cursors.Encode(store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	writeJSON(ctx, http.StatusOK, page)
+}
+
+// ListReplies godoc
+//
+//	@Summary	list replies
+//	@Schemes
+//	@Description	list the direct replies to a comment, oldest first, with cursor pagination
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string	true	"post id"
+//	@Param			commentID	path		string	true	"comment id"
+//	@Param			limit		query		int		false	"number of replies to return"	default(20)
+//	@Param			cursor		query		string	false	"cursor returned by the previous page"
+//	@Success		200			{object}	CommentsPage
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID}/replies [get]
+func (h *Handler) ListRepliesHandler(ctx *gin.Context) {
+	cq := store.PaginatedCursorQuery{
+		Limit: 20,
+	}
+
+	cq, err := cq.Parse(ctx, h.Cursors)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(cq); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	parent := commentFromCtx(ctx)
+
+	replies, err := h.Store.Comments.ListReplies(ctx, parent.ID, cq)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	page := CommentsPage{Comments: replies}
+	if len(replies) == cq.Limit {
+		last := replies[len(replies)-1]
+		page.NextCursor = h.Cursors.Encode(store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	writeJSON(ctx, http.StatusOK, page)
+}
+
+// CreateComment godoc
+//
+//	@Summary	create a comment
+//	@Schemes
+//	@Description	create a comment on a post, or a reply when parent_id is set
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string					true	"post id"
+//	@Param			payload	body		CreateCommentPayload	true	"comment payload"
+//	@Success		201		{object}	store.Comment
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments [post]
+func (h *Handler) CreateCommentHandler(ctx *gin.Context) {
+	var payload CreateCommentPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	author := userFromCtx(ctx)
+	post := postFromCtx(ctx)
+
+	comment := &store.Comment{
+		PostID:   post.ID,
+		AuthorID: author.ID,
+		Content:  payload.Content,
+		User: store.User{
+			ID:       author.ID,
+			Username: author.Username,
+		},
+	}
+
+	recipients := []string{post.AuthorID}
+	if payload.ParentID != nil {
+		parent, err := h.Store.Comments.GetByID(ctx, *payload.ParentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				h.badRequestErr(ctx, errors.New("parent comment not found"))
+				return
+			default:
+				h.internalServerErr(ctx, err)
+				return
+			}
+		}
+		if parent.HiddenAt != nil {
+			h.badRequestErr(ctx, errors.New("parent comment not found"))
+			return
+		}
+		if parent.PostID != post.ID {
+			h.badRequestErr(ctx, errors.New("parent comment belongs to another post"))
+			return
+		}
+
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+		recipients = append(recipients, parent.AuthorID)
+	}
+
+	// a block in either direction stops comments on the post and replies to
+	// the parent comment
+	for _, ownerID := range recipients {
+		blocked, err := h.Store.Blocks.IsBlocked(ctx, author.ID, ownerID)
+		if err != nil {
+			h.internalServerErr(ctx, err)
+			return
+		}
+		if blocked {
+			h.forbiddenErr(ctx)
+			return
+		}
+	}
+
+	if err := h.Store.Comments.Create(ctx, comment); err != nil {
+		switch {
+		case errors.Is(err, store.ErrMaxDepthExceeded):
+			h.badRequestErr(ctx, err)
+			return
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+			return
+		default:
+			h.internalServerErr(ctx, err)
+			return
+		}
+	}
+
+	writeJSON(ctx, http.StatusCreated, comment)
+}
+
+// UpdateComment godoc
+//
+//	@Summary	update a comment
+//	@Schemes
+//	@Description	update a comment by id
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string					true	"post id"
+//	@Param			commentID	path		string					true	"comment id"
+//	@Param			payload		body		UpdateCommentPayload	true	"comment payload"
+//	@Success		200			{object}	store.Comment
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID} [patch]
+func (h *Handler) UpdateCommentHandler(ctx *gin.Context) {
+	var payload UpdateCommentPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	comment := commentFromCtx(ctx)
+	comment.Content = payload.Content
+
+	if err := h.Store.Comments.Update(ctx, comment); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+			return
+		default:
+			h.internalServerErr(ctx, err)
+			return
+		}
+	}
+
+	writeJSON(ctx, http.StatusOK, comment)
+}
+
+// DeleteComment godoc
+//
+//	@Summary	delete a comment
+//	@Schemes
+//	@Description	delete a comment by id
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	string	true	"post id"
+//	@Param			commentID	path	string	true	"comment id"
+//	@Success		204			"No Content"
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID} [delete]
+func (h *Handler) DeleteCommentHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
+	comment := commentFromCtx(ctx)
+
+	if err := h.Store.Comments.Delete(ctx, comment.ID, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+			return
+		default:
+			h.internalServerErr(ctx, err)
+			return
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *Handler) CommentsContextMiddleware(ctx *gin.Context) {
+	id := ctx.Param("commentID")
+	comment, err := h.Store.Comments.GetByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+			ctx.Abort()
+			return
+		default:
+			h.internalServerErr(ctx, err)
+			ctx.Abort()
+			return
+		}
+	}
+
+	// the comment must belong to the post in the path
+	if comment.PostID != postFromCtx(ctx).ID {
+		h.notFoundErr(ctx, store.ErrNotFound)
+		ctx.Abort()
+		return
+	}
+
+	// hidden comments are only visible to moderators
+	if comment.HiddenAt != nil {
+		if !h.hasPermission(userFromCtx(ctx), "content:view:hidden") {
+			h.notFoundErr(ctx, store.ErrNotFound)
+			ctx.Abort()
+			return
+		}
+	}
+
+	ctx.Set("comment", comment)
+	ctx.Next()
+}
+
+func commentFromCtx(ctx *gin.Context) *store.Comment {
+	comment, ok := ctx.Get("comment")
+	if !ok {
+		return nil
+	}
+	return comment.(*store.Comment)
+}
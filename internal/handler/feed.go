@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/cprakhar/gopher-social/internal/store"
@@ -17,9 +18,12 @@ import (
 //	@Accept			json
 //	@Produce		json
 //	@Param			limit	query		int		false	"number of posts to return"	default(20)
-//	@Param			offset	query		int		false	"number of posts to skip"	default(0)
+//	@Param			offset	query		int		false	"number of posts to skip (deprecated, use cursor)"	default(0)
 //	@Param			sort	query		string	false	"sort order"				Enums(asc, desc)	default(desc)
+//	@Param			cursor	query		string	false	"cursor from the rel=next Link header of the previous page"
 //	@Success		200		{object}	[]store.PostWithMetadata
+//	@Header			200		{string}	Link	"rel=next link to the following page"
+//	@Failure		400		{object}	map[string]string
 //	@Failure		404		{object}	map[string]string
 //	@Failure		500		{object}	map[string]string
 //	@Security		ApiKeyAuth
@@ -31,7 +35,7 @@ func (h *Handler) GetUserFeedHandler(ctx *gin.Context) {
 		Sort:   "desc",
 	}
 
-	fp, err := fp.Parse(ctx)
+	fp, err := fp.Parse(ctx, h.Cursors)
 	if err != nil {
 		h.badRequestErr(ctx, err)
 		return
@@ -50,11 +54,34 @@ func (h *Handler) GetUserFeedHandler(ctx *gin.Context) {
 	// Get the user ID from auth context or session (stubbed here)
 	user := userFromCtx(ctx)
 
-	feed, err := h.Store.Posts.GetUserFeed(ctx, user.ID, fp)
+	var feed []store.PostWithMetadata
+	var next *store.Cursor
+	if h.usesTimeline(fp) {
+		feed, next, err = h.getTimelineFeed(ctx, user.ID, fp)
+	} else {
+		feed, err = h.Store.Posts.GetUserFeed(ctx, user.ID, fp)
+		if len(feed) == fp.Limit {
+			last := feed[len(feed)-1]
+			next = &store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+	}
 	if err != nil {
 		h.internalServerErr(ctx, err)
 		return
 	}
 
+	if next != nil {
+		h.setNextLink(ctx, *next)
+	}
+
 	writeJSON(ctx, http.StatusOK, feed)
 }
+
+// setNextLink points the rel=next Link header at the current request with
+// the cursor replaced and any offset dropped.
+func (h *Handler) setNextLink(ctx *gin.Context, next store.Cursor) {
+	query := ctx.Request.URL.Query()
+	query.Del("offset")
+	query.Set("cursor", h.Cursors.Encode(next))
+	ctx.Header("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, ctx.Request.URL.Path, query.Encode()))
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ListFollowRequests godoc
+//
+//	@Summary	list follow requests
+//	@Schemes
+//	@Description	list the pending requests to follow the current user, most recent first, with cursor pagination
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"number of requests to return"	default(20)
+//	@Param			cursor	query		string	false	"cursor returned by the previous page"
+//	@Success		200		{object}	FollowListPage
+//	@Failure		400		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/follow-requests [get]
+func (h *Handler) ListFollowRequestsHandler(ctx *gin.Context) {
+	pq := store.PaginatedCursorQuery{
+		Limit: 20,
+	}
+
+	pq, err := pq.Parse(ctx, h.Cursors)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(pq); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	user := userFromCtx(ctx)
+
+	entries, err := h.Store.Followers.ListRequests(ctx, user.ID, pq)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	page := FollowListPage{Users: entries}
+	if len(entries) == pq.Limit {
+		last := entries[len(entries)-1]
+		page.NextCursor = h.Cursors.Encode(store.Cursor{CreatedAt: last.FollowedAt, ID: last.ID})
+	}
+
+	writeJSON(ctx, http.StatusOK, page)
+}
+
+// ApproveFollowRequest godoc
+//
+//	@Summary	approve a follow request
+//	@Schemes
+//	@Description	let the requesting user follow the current user
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"id of the requesting user"
+//	@Success		204	"No Content"
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/follow-requests/{id} [put]
+func (h *Handler) ApproveFollowRequestHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
+	requesterID := ctx.Param("id")
+
+	if err := h.Store.Followers.ApproveRequest(ctx, user.ID, requesterID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	if h.Cfg.Redis.Enabled {
+		if err := h.backfillTimeline(ctx, requesterID, user.ID); err != nil {
+			h.Logger.Errorw("error backfilling timeline", "user_id", requesterID, "following_id", user.ID, "error", err)
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RejectFollowRequest godoc
+//
+//	@Summary	reject a follow request
+//	@Schemes
+//	@Description	discard a pending request to follow the current user
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"id of the requesting user"
+//	@Success		204	"No Content"
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/follow-requests/{id} [delete]
+func (h *Handler) RejectFollowRequestHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
+
+	if err := h.Store.Followers.RejectRequest(ctx, user.ID, ctx.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
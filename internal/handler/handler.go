@@ -20,7 +20,13 @@ type Handler struct {
 	Mailer        mail.Client
 	Authenticator auth.Authenticator
 	CacheStorage  cache.Store
-	RateLimiter   *ratelimiter.FixedWindowRateLimiter
+	RateLimiter   ratelimiter.Limiter
+	Cursors       store.CursorCodec
+	Roles         *store.RoleRegistry
+
+	// AccountLockout and IPLockout track failed logins.
+	AccountLockout ratelimiter.Lockout
+	IPLockout      ratelimiter.Lockout
 }
 
 func writeJSON(ctx *gin.Context, status int, data any) {
@@ -51,6 +57,18 @@ func (h *Handler) conflictErr(ctx *gin.Context, err error) {
 	ctx.JSON(http.StatusConflict, gin.H{"error": "resource already exists"})
 }
 
+// stateConflictErr reports a request that conflicts with the current state
+// of a resource, other than it already existing.
+func (h *Handler) stateConflictErr(ctx *gin.Context, err error) {
+	h.Logger.Warnw("conflict error", "method", ctx.Request.Method, "path", ctx.Request.URL.Path, "error", err.Error())
+	ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+}
+
+func (h *Handler) preconditionFailedErr(ctx *gin.Context, err error) {
+	h.Logger.Warnw("precondition failed error", "method", ctx.Request.Method, "path", ctx.Request.URL.Path, "error", err.Error())
+	ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": "resource was modified, reload it and try again"})
+}
+
 func (h *Handler) unauthorizedErr(ctx *gin.Context, err error) {
 	h.Logger.Errorw("unauthorized error", "method", ctx.Request.Method, "path", ctx.Request.URL.Path, "error", err.Error())
 
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cprakhar/gopher-social/internal/store"
 	"github.com/gin-gonic/gin"
@@ -47,30 +50,40 @@ func (h *Handler) BasicAuthMiddleware(ctx *gin.Context) {
 }
 
 func (h *Handler) AuthTokenMiddleware(ctx *gin.Context) {
-	authHeader := ctx.GetHeader("Authorization")
-	if authHeader == "" {
-		h.unauthorizedErr(ctx, fmt.Errorf("authorization header is missing"))
+	token, err := h.bearerToken(ctx)
+	if err != nil {
+		h.unauthorizedErr(ctx, err)
 		ctx.Abort()
 		return
 	}
 
-	parts := strings.SplitN(authHeader, " ", 2)
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		h.unauthorizedErr(ctx, fmt.Errorf("authorization header is malformed"))
+	userID, err := token.Claims.GetSubject()
+	if err != nil || userID == "" {
+		h.unauthorizedErr(ctx, fmt.Errorf("token subject is missing"))
 		ctx.Abort()
 		return
 	}
 
-	token, err := h.Authenticator.ValidateToken(parts[1])
-	if err != nil {
-		h.unauthorizedErr(ctx, err)
+	claims, _ := token.Claims.(jwt.MapClaims)
+	tokenID, _ := claims["jti"].(string)
+	if tokenID == "" {
+		h.unauthorizedErr(ctx, fmt.Errorf("token id is missing"))
 		ctx.Abort()
 		return
 	}
 
-	claims, _ := token.Claims.(jwt.RegisteredClaims)
+	revoked, err := h.isTokenRevoked(ctx, tokenID)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		ctx.Abort()
+		return
+	}
+	if revoked {
+		h.unauthorizedErr(ctx, fmt.Errorf("token has been revoked"))
+		ctx.Abort()
+		return
+	}
 
-	userID := claims.Subject
 	user, err := h.getUser(ctx, userID)
 	if err != nil {
 		h.unauthorizedErr(ctx, err)
@@ -78,42 +91,74 @@ func (h *Handler) AuthTokenMiddleware(ctx *gin.Context) {
 		return
 	}
 
+	if user.IsSuspended() {
+		h.forbiddenErr(ctx)
+		ctx.Abort()
+		return
+	}
+
 	ctx.Set("user", user)
 	ctx.Next()
 }
 
-func (h *Handler) CheckPostOwnership(requiredRole string, next gin.HandlerFunc) gin.HandlerFunc {
+func (h *Handler) CheckPostOwnership(permission string, next gin.HandlerFunc) gin.HandlerFunc {
+	return h.checkOwnership(permission, func(ctx *gin.Context) string {
+		return postFromCtx(ctx).AuthorID
+	}, next)
+}
+
+func (h *Handler) CheckCommentOwnership(permission string, next gin.HandlerFunc) gin.HandlerFunc {
+	return h.checkOwnership(permission, func(ctx *gin.Context) string {
+		return commentFromCtx(ctx).AuthorID
+	}, next)
+}
+
+// checkOwnership lets the owner of a resource through, and anyone else only if
+// their role grants permission.
+func (h *Handler) checkOwnership(permission string, ownerID func(*gin.Context) string, next gin.HandlerFunc) gin.HandlerFunc {
 	return gin.HandlerFunc(func(ctx *gin.Context) {
 		user := userFromCtx(ctx)
-		post := postFromCtx(ctx)
 
-		if post.AuthorID == user.ID {
+		if ownerID(ctx) == user.ID {
 			next(ctx)
 			return
 		}
 
-		allowed, err := h.checkRolePrecedence(ctx, user, requiredRole)
-		if err != nil {
-			h.internalServerErr(ctx, err)
+		if !h.hasPermission(user, permission) {
+			h.forbiddenErr(ctx)
 			ctx.Abort()
 			return
 		}
-		if !allowed {
+		next(ctx)
+	})
+}
+
+// RequirePermission only lets through users whose role grants permission.
+func (h *Handler) RequirePermission(permission string) gin.HandlerFunc {
+	return gin.HandlerFunc(func(ctx *gin.Context) {
+		if !h.hasPermission(userFromCtx(ctx), permission) {
 			h.forbiddenErr(ctx)
 			ctx.Abort()
 			return
 		}
-		next(ctx)
+		ctx.Next()
 	})
 }
 
-func (h *Handler) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
-	role, err := h.Store.Roles.GetByName(ctx, roleName)
-	if err != nil {
-		return false, err
+// canView reports whether the viewer may see content owned by ownerID.
+// Roles granted user:view:private, such as moderators, can see private
+// accounts so they can act on them.
+func (h *Handler) canView(ctx context.Context, viewer *store.User, ownerID string) (bool, error) {
+	visible, err := h.Store.Followers.CanView(ctx, viewer.ID, ownerID)
+	if err != nil || visible {
+		return visible, err
 	}
 
-	return user.Role.Level >= role.Level, nil
+	return h.hasPermission(viewer, "user:view:private"), nil
+}
+
+func (h *Handler) hasPermission(user *store.User, permission string) bool {
+	return h.Roles.HasPermission(user.Role.ID, permission)
 }
 
 func (h *Handler) getUser(ctx context.Context, userID string) (*store.User, error) {
@@ -140,14 +185,79 @@ func (h *Handler) getUser(ctx context.Context, userID string) (*store.User, erro
 	return user, nil
 }
 
+func (h *Handler) isTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if !h.Cfg.Redis.Enabled {
+		return h.Store.RefreshTokens.IsAccessTokenRevoked(ctx, tokenID)
+	}
+
+	return h.CacheStorage.Tokens.IsRevoked(ctx, tokenID)
+}
+
+// bearerToken validates the bearer token of the request. Valid tokens are
+// kept on the context so they are only verified once per request.
+func (h *Handler) bearerToken(ctx *gin.Context) (*jwt.Token, error) {
+	if token, ok := ctx.Get("token"); ok {
+		return token.(*jwt.Token), nil
+	}
+
+	authHeader := ctx.GetHeader("Authorization")
+	if authHeader == "" {
+		return nil, fmt.Errorf("authorization header is missing")
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, fmt.Errorf("authorization header is malformed")
+	}
+
+	token, err := h.Authenticator.ValidateToken(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.Set("token", token)
+	return token, nil
+}
+
+// RateLimiterMiddleware applies the rate limit policy of the matched route.
+// Clients with a valid access token are limited per user, everyone else per
+// IP address.
 func (h *Handler) RateLimiterMiddleware(ctx *gin.Context) {
-	if h.Cfg.RateLimiter.Enabled {
-		allow, retryAfter := h.RateLimiter.Allow(ctx.ClientIP())
-		if !allow {
-			h.tooManyRequestsErr(ctx, retryAfter.String())
-			ctx.Abort()
-			return
+	if !h.Cfg.RateLimiter.Enabled {
+		ctx.Next()
+		return
+	}
+
+	key := "ip:" + ctx.ClientIP()
+	if token, err := h.bearerToken(ctx); err == nil {
+		if userID, err := token.Claims.GetSubject(); err == nil && userID != "" {
+			key = "user:" + userID
 		}
 	}
+
+	policy := h.Cfg.RateLimiter.PolicyFor(ctx.Request.Method, ctx.FullPath())
+	res, err := h.RateLimiter.Allow(ctx, key, policy)
+	if err != nil {
+		// fail open: an unavailable limiter backend should not take the
+		// API down with it
+		h.Logger.Errorw("rate limiter error", "method", ctx.Request.Method, "path", ctx.Request.URL.Path, "error", err.Error())
+		ctx.Next()
+		return
+	}
+
+	ctx.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	ctx.Header("RateLimit-Reset", seconds(res.Reset))
+
+	if !res.Allowed {
+		h.tooManyRequestsErr(ctx, seconds(res.RetryAfter))
+		ctx.Abort()
+		return
+	}
 	ctx.Next()
 }
+
+// seconds formats d as whole seconds, rounded up, for use in headers.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
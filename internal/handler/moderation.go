@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type ReportsPage struct {
+	Reports    []store.Report `json:"reports"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type AuditLogPage struct {
+	Entries    []store.AuditEntry `json:"entries"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+type ResolveReportPayload struct {
+	Action      string `json:"action" binding:"required,oneof=dismiss hide warn suspend"`
+	Note        string `json:"note" binding:"max=1000"`
+	SuspendDays int    `json:"suspend_days" binding:"required_if=Action suspend,omitempty,min=1,max=365"`
+}
+
+// ListReports godoc
+//
+//	@Summary	list reports
+//	@Schemes
+//	@Description	list the moderation queue, oldest report first, with cursor pagination
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			status	query		string	false	"report status"					Enums(open, claimed, resolved)	default(open)
+//	@Param			limit	query		int		false	"number of reports to return"	default(20)
+//	@Param			cursor	query		string	false	"cursor returned by the previous page"
+//	@Success		200		{object}	ReportsPage
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports [get]
+func (h *Handler) ListReportsHandler(ctx *gin.Context) {
+	status := ctx.DefaultQuery("status", "open")
+	if err := validator.New().Var(status, "oneof=open claimed resolved"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	pq := store.PaginatedCursorQuery{
+		Limit: 20,
+	}
+
+	pq, err := pq.Parse(ctx, h.Cursors)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(pq); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	reports, err := h.Store.Reports.List(ctx, status, pq)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	page := ReportsPage{Reports: reports}
+	if len(reports) == pq.Limit {
+		last := reports[len(reports)-1]
+		page.NextCursor = h.Cursors.Encode(store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	writeJSON(ctx, http.StatusOK, page)
+}
+
+// ClaimReport godoc
+//
+//	@Summary	claim a report
+//	@Schemes
+//	@Description	assign an open report to the current moderator
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			reportID	path		string	true	"report id"
+//	@Success		200			{object}	store.Report
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports/{reportID}/claim [put]
+func (h *Handler) ClaimReportHandler(ctx *gin.Context) {
+	reportID := ctx.Param("reportID")
+	if err := validator.New().Var(reportID, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	moderator := userFromCtx(ctx)
+
+	report, err := h.Store.Reports.Claim(ctx, reportID, moderator.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		case errors.Is(err, store.ErrConflict):
+			h.conflictErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	writeJSON(ctx, http.StatusOK, report)
+}
+
+// ResolveReport godoc
+//
+//	@Summary	resolve a report
+//	@Schemes
+//	@Description	close a claimed report by dismissing it, hiding the reported content, warning its author or suspending its author
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			reportID	path		string					true	"report id"
+//	@Param			payload		body		ResolveReportPayload	true	"resolution"
+//	@Success		200			{object}	store.Report
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports/{reportID}/resolve [put]
+func (h *Handler) ResolveReportHandler(ctx *gin.Context) {
+	reportID := ctx.Param("reportID")
+	if err := validator.New().Var(reportID, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	var payload ResolveReportPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	moderator := userFromCtx(ctx)
+
+	res := store.Resolution{
+		Action: payload.Action,
+		Note:   payload.Note,
+	}
+	if payload.Action == "suspend" {
+		until := time.Now().Add(time.Duration(payload.SuspendDays) * 24 * time.Hour)
+		res.SuspendedUntil = &until
+	}
+
+	report, subjectID, err := h.Store.Reports.Resolve(ctx, reportID, moderator.ID, res)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidAction):
+			h.badRequestErr(ctx, err)
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		case errors.Is(err, store.ErrConflict), errors.Is(err, store.ErrReportNotClaimed):
+			h.conflictErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	// drop the cached copy so the suspension applies to the next request
+	if payload.Action == "suspend" && h.Cfg.Redis.Enabled {
+		if err := h.CacheStorage.Users.Delete(ctx, subjectID); err != nil {
+			h.Logger.Errorw("error invalidating cached user", "user_id", subjectID, "error", err)
+		}
+	}
+
+	writeJSON(ctx, http.StatusOK, report)
+}
+
+// ListAuditLog godoc
+//
+//	@Summary	list the audit log
+//	@Schemes
+//	@Description	list moderation actions, most recent first, with cursor pagination
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"number of entries to return"	default(20)
+//	@Param			cursor	query		string	false	"cursor returned by the previous page"
+//	@Success		200		{object}	AuditLogPage
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/audit-log [get]
+func (h *Handler) ListAuditLogHandler(ctx *gin.Context) {
+	pq := store.PaginatedCursorQuery{
+		Limit: 20,
+	}
+
+	pq, err := pq.Parse(ctx, h.Cursors)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(pq); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	entries, err := h.Store.AuditLog.List(ctx, pq)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	page := AuditLogPage{Entries: entries}
+	if len(entries) == pq.Limit {
+		last := entries[len(entries)-1]
+		page.NextCursor = h.Cursors.Encode(store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	writeJSON(ctx, http.StatusOK, page)
+}
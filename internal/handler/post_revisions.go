@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/cprakhar/gopher-social/internal/diff"
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// RevisionDiff describes what an edit changed compared to the version it
+// replaced.
+type RevisionDiff struct {
+	FromVersion int       `json:"from_version"`
+	Title       []diff.Op `json:"title"`
+	Content     []diff.Op `json:"content"`
+	TagsAdded   []string  `json:"tags_added"`
+	TagsRemoved []string  `json:"tags_removed"`
+}
+
+type PostRevisionEntry struct {
+	store.PostRevision
+	// Diff is nil for the first recorded version of a post.
+	Diff *RevisionDiff `json:"diff"`
+}
+
+type PostRevisionsPage struct {
+	Revisions  []PostRevisionEntry `json:"revisions"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// ListPostRevisions godoc
+//
+//	@Summary	list the revisions of a post
+//	@Schemes
+//	@Description	list every saved version of a post, newest first, each with a line diff against the version it replaced
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"post id"
+//	@Param			limit	query		int		false	"number of revisions to return"	default(20)
+//	@Param			cursor	query		string	false	"cursor returned by the previous page"
+//	@Success		200		{object}	PostRevisionsPage
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions [get]
+func (h *Handler) ListPostRevisionsHandler(ctx *gin.Context) {
+	post := postFromCtx(ctx)
+
+	pq := store.PaginatedCursorQuery{
+		Limit: 20,
+	}
+
+	pq, err := pq.Parse(ctx, h.Cursors)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(pq); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	revisions, err := h.Store.Posts.ListRevisions(ctx, post.ID, pq)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	page := PostRevisionsPage{Revisions: make([]PostRevisionEntry, len(revisions))}
+	for i, r := range revisions {
+		page.Revisions[i] = PostRevisionEntry{PostRevision: r}
+		if r.Previous != nil {
+			page.Revisions[i].Diff = diffRevisions(r.Previous, &r)
+		}
+	}
+
+	if len(revisions) == pq.Limit {
+		last := revisions[len(revisions)-1]
+		page.NextCursor = h.Cursors.Encode(store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	writeJSON(ctx, http.StatusOK, page)
+}
+
+func diffRevisions(from, to *store.PostRevision) *RevisionDiff {
+	d := &RevisionDiff{
+		FromVersion: from.Version,
+		Title:       diff.Lines(from.Title, to.Title),
+		Content:     diff.Lines(from.Content, to.Content),
+		TagsAdded:   []string{},
+		TagsRemoved: []string{},
+	}
+
+	for _, tag := range to.Tags {
+		if !slices.Contains(from.Tags, tag) {
+			d.TagsAdded = append(d.TagsAdded, tag)
+		}
+	}
+	for _, tag := range from.Tags {
+		if !slices.Contains(to.Tags, tag) {
+			d.TagsRemoved = append(d.TagsRemoved, tag)
+		}
+	}
+
+	return d
+}
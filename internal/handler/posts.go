@@ -3,6 +3,8 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/cprakhar/gopher-social/internal/store"
 	"github.com/gin-gonic/gin"
@@ -48,6 +50,10 @@ func (h *Handler) CreatePostHandler(ctx *gin.Context) {
 		return
 	}
 
+	if h.Cfg.Redis.Enabled {
+		h.fanOutPostAsync(*post)
+	}
+
 	writeJSON(ctx, http.StatusCreated, post)
 }
 
@@ -67,7 +73,13 @@ func (h *Handler) CreatePostHandler(ctx *gin.Context) {
 func (h *Handler) GetPostHandler(ctx *gin.Context) {
 	post := postFromCtx(ctx)
 
-	comments, err := h.Store.Comments.GetByPostID(ctx, post.ID)
+	// load a bounded slice of the thread; the rest is paged through the
+	// comments endpoints
+	comments, err := h.Store.Comments.GetThread(ctx, post.ID, store.ThreadQuery{
+		RootLimit:    20,
+		RepliesLimit: 3,
+		MaxDepth:     store.MaxCommentDepth,
+	})
 	if err != nil {
 		h.internalServerErr(ctx, err)
 		return
@@ -75,6 +87,7 @@ func (h *Handler) GetPostHandler(ctx *gin.Context) {
 
 	post.Comments = comments
 
+	ctx.Header("ETag", postETag(post))
 	writeJSON(ctx, http.StatusOK, post)
 }
 
@@ -82,7 +95,7 @@ func (h *Handler) GetPostHandler(ctx *gin.Context) {
 //
 //	@Summary	delete a post
 //	@Schemes
-//	@Description	delete a post by id
+//	@Description	delete a post by id; admins can restore it until it is purged
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -93,9 +106,10 @@ func (h *Handler) GetPostHandler(ctx *gin.Context) {
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [delete]
 func (h *Handler) DeletePostHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
 	post := postFromCtx(ctx)
 
-	if err := h.Store.Posts.Delete(ctx, post.ID); err != nil {
+	if err := h.Store.Posts.Delete(ctx, post.ID, user.ID); err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
 			h.notFoundErr(ctx, err)
@@ -119,15 +133,18 @@ type UpdatePostPayload struct {
 //
 //	@Summary	update a post
 //	@Schemes
-//	@Description	update a post by id
+//	@Description	update a post by id; send the post's ETag in If-Match to avoid overwriting concurrent edits
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		path		string				true	"post id"
-//	@Param			payload	body		UpdatePostPayload	true	"post payload"
-//	@Success		200		{object}	store.Post
-//	@Failure		400		{object}	map[string]string
-//	@Failure		500		{object}	map[string]string
+//	@Param			id			path		string				true	"post id"
+//	@Param			If-Match	header		string				false	"ETag of the version being edited"
+//	@Param			payload		body		UpdatePostPayload	true	"post payload"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		412			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [patch]
 func (h *Handler) UpdatePostHandler(ctx *gin.Context) {
@@ -137,8 +154,14 @@ func (h *Handler) UpdatePostHandler(ctx *gin.Context) {
 		return
 	}
 
+	user := userFromCtx(ctx)
 	post := postFromCtx(ctx)
 
+	if ifMatch := ctx.GetHeader("If-Match"); ifMatch != "" && !etagMatches(ifMatch, postETag(post)) {
+		h.preconditionFailedErr(ctx, store.ErrVersionConflict)
+		return
+	}
+
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
@@ -149,14 +172,40 @@ func (h *Handler) UpdatePostHandler(ctx *gin.Context) {
 		post.Tags = payload.Tags
 	}
 
-	if err := h.Store.Posts.Update(ctx, post); err != nil {
-		h.internalServerErr(ctx, err)
+	if err := h.Store.Posts.Update(ctx, post, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrVersionConflict):
+			h.preconditionFailedErr(ctx, err)
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
 		return
 	}
 
+	ctx.Header("ETag", postETag(post))
 	writeJSON(ctx, http.StatusOK, post)
 }
 
+// postETag derives a strong entity tag from the post's version, which is
+// bumped on every edit.
+func postETag(post *store.Post) string {
+	return `"` + strconv.Itoa(post.Version) + `"`
+}
+
+// etagMatches reports whether an If-Match header matches etag. Weak tags
+// never match, as If-Match requires strong comparison.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *Handler) PostsContextMiddleware(ctx *gin.Context) {
 	id := ctx.Param("id")
 	post, err := h.Store.Posts.GetByID(ctx, id)
@@ -173,6 +222,23 @@ func (h *Handler) PostsContextMiddleware(ctx *gin.Context) {
 		}
 	}
 
+	// posts of private accounts are reported as missing to anyone who may
+	// not see them, and hidden posts to anyone but moderators
+	visible, err := h.canView(ctx, userFromCtx(ctx), post.AuthorID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		h.internalServerErr(ctx, err)
+		ctx.Abort()
+		return
+	}
+	if visible && post.HiddenAt != nil {
+		visible = h.hasPermission(userFromCtx(ctx), "content:view:hidden")
+	}
+	if !visible {
+		h.notFoundErr(ctx, store.ErrNotFound)
+		ctx.Abort()
+		return
+	}
+
 	ctx.Set("post", post)
 	ctx.Next()
 }
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+type ReactionPayload struct {
+	Type string `json:"type" binding:"required,oneof=like love laugh wow sad angry"`
+}
+
+// SetReaction godoc
+//
+//	@Summary	react to a post
+//	@Schemes
+//	@Description	set the current user's reaction to a post, replacing any previous one
+//	@Tags			reactions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string			true	"post id"
+//	@Param			payload	body		ReactionPayload	true	"reaction payload"
+//	@Success		200		{object}	store.Reaction
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions [put]
+func (h *Handler) SetReactionHandler(ctx *gin.Context) {
+	var payload ReactionPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	user := userFromCtx(ctx)
+	post := postFromCtx(ctx)
+
+	reaction := &store.Reaction{
+		PostID: post.ID,
+		UserID: user.ID,
+		Type:   payload.Type,
+	}
+
+	if err := h.Store.Reactions.Set(ctx, reaction); err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	writeJSON(ctx, http.StatusOK, reaction)
+}
+
+// DeleteReaction godoc
+//
+//	@Summary	remove a reaction
+//	@Schemes
+//	@Description	remove the current user's reaction to a post
+//	@Tags			reactions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"post id"
+//	@Success		204	"No Content"
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions [delete]
+func (h *Handler) DeleteReactionHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
+	post := postFromCtx(ctx)
+
+	if err := h.Store.Reactions.Delete(ctx, post.ID, user.ID); err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateReportPayload struct {
+	TargetType string `json:"target_type" binding:"required,oneof=post comment user"`
+	TargetID   string `json:"target_id" binding:"required,uuid"`
+	Reason     string `json:"reason" binding:"required,oneof=spam harassment hate violence nudity other"`
+	Details    string `json:"details" binding:"max=1000"`
+}
+
+// CreateReport godoc
+//
+//	@Summary	report content
+//	@Schemes
+//	@Description	report a post, comment or user to the moderators
+//	@Tags			reports
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateReportPayload	true	"report payload"
+//	@Success		201		{object}	store.Report
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		409		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/reports [post]
+func (h *Handler) CreateReportHandler(ctx *gin.Context) {
+	var payload CreateReportPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	user := userFromCtx(ctx)
+
+	report := &store.Report{
+		ReporterID: user.ID,
+		TargetType: payload.TargetType,
+		TargetID:   payload.TargetID,
+		Reason:     payload.Reason,
+		Details:    payload.Details,
+	}
+
+	if err := h.Store.Reports.Create(ctx, report); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		case errors.Is(err, store.ErrConflict):
+			h.conflictErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	writeJSON(ctx, http.StatusCreated, report)
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type SetRolePermissionsPayload struct {
+	Permissions []string `json:"permissions" validate:"unique,dive,required"`
+}
+
+type AssignRolePayload struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// ListRoles godoc
+//
+//	@Summary	list roles
+//	@Schemes
+//	@Description	list every role with the permissions granted to it
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		store.Role
+//	@Failure		403	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles [get]
+func (h *Handler) ListRolesHandler(ctx *gin.Context) {
+	roles, err := h.Store.Roles.List(ctx)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	writeJSON(ctx, http.StatusOK, roles)
+}
+
+// ListPermissions godoc
+//
+//	@Summary	list permissions
+//	@Schemes
+//	@Description	list every permission that can be granted to a role
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		store.Permission
+//	@Failure		403	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/admin/permissions [get]
+func (h *Handler) ListPermissionsHandler(ctx *gin.Context) {
+	permissions, err := h.Store.Roles.ListPermissions(ctx)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	writeJSON(ctx, http.StatusOK, permissions)
+}
+
+// SetRolePermissions godoc
+//
+//	@Summary	set the permissions of a role
+//	@Schemes
+//	@Description	replace the permissions granted to a role; at least one role must keep role:manage
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	path		string						true	"role name"
+//	@Param			payload	body		SetRolePermissionsPayload	true	"permissions payload"
+//	@Success		200		{object}	store.Role
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		409		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles/{name}/permissions [put]
+func (h *Handler) SetRolePermissionsHandler(ctx *gin.Context) {
+	var payload SetRolePermissionsPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	admin := userFromCtx(ctx)
+
+	role, err := h.Store.Roles.SetPermissions(ctx, ctx.Param("name"), payload.Permissions, admin.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUnknownPermission):
+			h.badRequestErr(ctx, err)
+		case errors.Is(err, store.ErrLastRoleManager):
+			h.stateConflictErr(ctx, err)
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	writeJSON(ctx, http.StatusOK, role)
+}
+
+// AssignRole godoc
+//
+//	@Summary	assign a role to a user
+//	@Schemes
+//	@Description	change the role of a user; admins cannot change their own role
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	string				true	"user id"
+//	@Param			payload	body	AssignRolePayload	true	"role payload"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{id}/role [put]
+func (h *Handler) AssignRoleHandler(ctx *gin.Context) {
+	var payload AssignRolePayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	userID := ctx.Param("id")
+	if err := validator.New().Var(userID, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	// an admin demoting themselves could leave nobody able to manage roles
+	admin := userFromCtx(ctx)
+	if admin.ID == userID {
+		h.forbiddenErr(ctx)
+		return
+	}
+
+	if err := h.Store.Roles.Assign(ctx, userID, payload.Role, admin.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	// drop the cached copy so the new role applies to the next request
+	if h.Cfg.Redis.Enabled {
+		if err := h.CacheStorage.Users.Delete(ctx, userID); err != nil {
+			h.Logger.Errorw("error invalidating cached user", "user_id", userID, "error", err)
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type SearchResults struct {
+	Posts []store.PostSearchResult `json:"posts,omitempty"`
+	Users []store.User             `json:"users,omitempty"`
+}
+
+// Search godoc
+//
+//	@Summary	search posts and users
+//	@Schemes
+//	@Description	full-text search over all posts, ranked by relevance, and username prefix search over users
+//	@Tags			search
+//	@Accept			json
+//	@Produce		json
+//	@Param			q		query		string	true	"search terms"
+//	@Param			type	query		string	false	"what to search"				Enums(all, posts, users)	default(all)
+//	@Param			limit	query		int		false	"number of results to return"	default(20)
+//	@Param			offset	query		int		false	"number of results to skip"		default(0)
+//	@Success		200		{object}	SearchResults
+//	@Failure		400		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+func (h *Handler) SearchHandler(ctx *gin.Context) {
+	sq := store.SearchQuery{
+		Type:  "all",
+		Limit: 20,
+	}
+
+	sq, err := sq.Parse(ctx)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(sq); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	user := userFromCtx(ctx)
+
+	var results SearchResults
+
+	if sq.Type == "all" || sq.Type == "posts" {
+		results.Posts, err = h.Store.Posts.Search(ctx, user.ID, sq)
+		if err != nil {
+			h.internalServerErr(ctx, err)
+			return
+		}
+	}
+
+	if sq.Type == "all" || sq.Type == "users" {
+		results.Users, err = h.Store.Users.SearchByUsername(ctx, sq.Query, sq.Limit, sq.Offset)
+		if err != nil {
+			h.internalServerErr(ctx, err)
+			return
+		}
+	}
+
+	writeJSON(ctx, http.StatusOK, results)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// GetTrendingTags godoc
+//
+//	@Summary	get trending tags
+//	@Schemes
+//	@Description	rank tags by how many posts used them inside a sliding window, with recent posts weighted more
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			window	query		string	false	"sliding window"				Enums(1h, 24h, 7d)	default(24h)
+//	@Param			limit	query		int		false	"number of tags to return"	default(10)
+//	@Success		200		{object}	[]store.TrendingTag
+//	@Failure		400		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/tags/trending [get]
+func (h *Handler) GetTrendingTagsHandler(ctx *gin.Context) {
+	tq := store.TrendingTagsQuery{
+		Window: "24h",
+		Limit:  10,
+	}
+
+	tq, err := tq.Parse(ctx)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(tq); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	tags, err := h.Store.Tags.Trending(ctx, tq)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	writeJSON(ctx, http.StatusOK, tags)
+}
+
+// GetTagPosts godoc
+//
+//	@Summary	get posts for a tag
+//	@Schemes
+//	@Description	list the posts carrying a tag with cursor pagination
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag		path		string	true	"tag"
+//	@Param			limit	query		int		false	"number of posts to return"	default(20)
+//	@Param			sort	query		string	false	"sort order"				Enums(asc, desc)	default(desc)
+//	@Param			cursor	query		string	false	"cursor from the rel=next Link header of the previous page"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Header			200		{string}	Link	"rel=next link to the following page"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/posts [get]
+func (h *Handler) GetTagPostsHandler(ctx *gin.Context) {
+	tag := ctx.Param("tag")
+	if err := validator.New().Var(tag, "required,max=100"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	fp := store.PaginatedFeedQuery{
+		Limit: 20,
+		Sort:  "desc",
+	}
+
+	fp, err := fp.Parse(ctx, h.Cursors)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(fp); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	user := userFromCtx(ctx)
+
+	posts, err := h.Store.Posts.GetByTag(ctx, user.ID, tag, fp)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	if len(posts) == fp.Limit {
+		last := posts[len(posts)-1]
+		h.setNextLink(ctx, store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	writeJSON(ctx, http.StatusOK, posts)
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+)
+
+// Feed timelines are fanned out on write: a new post is pushed into a Redis
+// sorted set per follower, so reading a feed is a range query plus a batched
+// lookup of the posts. Authors with more than Timeline.FanoutLimit followers
+// are not fanned out; their posts are pulled from Postgres at read time and
+// merged in. Timelines are built lazily on first read and left to expire.
+
+const fanOutTimeout = 30 * time.Second
+
+// fanOutPostAsync runs fanOutPost in the background so creating a post does
+// not wait on Redis.
+func (h *Handler) fanOutPostAsync(post store.Post) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), fanOutTimeout)
+		defer cancel()
+
+		if err := h.fanOutPost(ctx, &post); err != nil {
+			h.Logger.Errorw("error fanning out post", "post_id", post.ID, "error", err)
+		}
+	}()
+}
+
+func (h *Handler) fanOutPost(ctx context.Context, post *store.Post) error {
+	entry := store.TimelineEntry{PostID: post.ID, CreatedAt: post.CreatedAt}
+
+	count, err := h.Store.Followers.CountFollowers(ctx, post.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	celebrity := count > h.Cfg.Timeline.FanoutLimit
+	if err := h.CacheStorage.Timelines.SetCelebrity(ctx, post.AuthorID, celebrity); err != nil {
+		return err
+	}
+
+	userIDs := []string{post.AuthorID}
+	if !celebrity {
+		followerIDs, err := h.Store.Followers.GetFollowerIDs(ctx, post.AuthorID)
+		if err != nil {
+			return err
+		}
+		userIDs = append(userIDs, followerIDs...)
+	}
+
+	return h.CacheStorage.Timelines.Push(ctx, userIDs, entry, h.Cfg.Timeline.MaxLength)
+}
+
+// backfillTimeline adds the recent posts of a newly followed author to the
+// follower's timeline. Celebrity posts are pulled at read time instead.
+func (h *Handler) backfillTimeline(ctx context.Context, userID, authorID string) error {
+	celebrity, err := h.CacheStorage.Timelines.IsCelebrity(ctx, authorID)
+	if err != nil || celebrity {
+		return err
+	}
+
+	entries, err := h.Store.Posts.GetEntriesByAuthors(ctx, []string{authorID}, nil, h.Cfg.Timeline.MaxLength)
+	if err != nil {
+		return err
+	}
+
+	return h.CacheStorage.Timelines.Backfill(ctx, userID, entries, h.Cfg.Timeline.MaxLength)
+}
+
+// pruneTimeline removes the posts of an unfollowed author from the follower's
+// timeline.
+func (h *Handler) pruneTimeline(ctx context.Context, userID, authorID string) error {
+	entries, err := h.Store.Posts.GetEntriesByAuthors(ctx, []string{authorID}, nil, h.Cfg.Timeline.MaxLength)
+	if err != nil {
+		return err
+	}
+
+	postIDs := make([]string, len(entries))
+	for i, entry := range entries {
+		postIDs[i] = entry.PostID
+	}
+
+	return h.CacheStorage.Timelines.Remove(ctx, userID, postIDs)
+}
+
+// getTimelineFeed serves a feed page from the user's timeline, merged with
+// the posts of followed celebrity authors. The returned cursor is nil on the
+// last page.
+func (h *Handler) getTimelineFeed(ctx context.Context, userID string, fp store.PaginatedFeedQuery) ([]store.PostWithMetadata, *store.Cursor, error) {
+	entries, ok, err := h.CacheStorage.Timelines.Range(ctx, userID, fp.Cursor, fp.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !ok {
+		all, err := h.Store.Posts.GetTimelineEntries(ctx, userID, h.Cfg.Timeline.MaxLength)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := h.CacheStorage.Timelines.Build(ctx, userID, all); err != nil {
+			return nil, nil, err
+		}
+		entries = pageTimelineEntries(all, fp.Cursor, fp.Limit)
+	}
+
+	celebrities, err := h.CacheStorage.Timelines.Celebrities(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(celebrities) > 0 {
+		followed, err := h.Store.Followers.FilterFollowing(ctx, userID, celebrities)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(followed) > 0 {
+			pulled, err := h.Store.Posts.GetEntriesByAuthors(ctx, followed, fp.Cursor, fp.Limit)
+			if err != nil {
+				return nil, nil, err
+			}
+			entries = mergeTimelineEntries(entries, pulled, fp.Limit)
+		}
+	}
+
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.PostID
+	}
+
+	feed, err := h.Store.Posts.GetFeedByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *store.Cursor
+	if len(entries) == fp.Limit {
+		last := entries[len(entries)-1]
+		next = &store.Cursor{CreatedAt: last.CreatedAt, ID: last.PostID}
+	}
+
+	return feed, next, nil
+}
+
+// usesTimeline reports whether the feed query can be answered from a
+// timeline. Searches, filters and legacy offsets go to Postgres.
+func (h *Handler) usesTimeline(fp store.PaginatedFeedQuery) bool {
+	return h.Cfg.Redis.Enabled &&
+		fp.Sort == "desc" &&
+		fp.Offset == 0 &&
+		fp.Search == "" &&
+		len(fp.Tags) == 0 &&
+		fp.Since == nil &&
+		fp.Until == nil
+}
+
+// timelineEntryBefore orders entries newest first, by (created_at, id).
+func timelineEntryBefore(a, b store.TimelineEntry) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.PostID > b.PostID
+}
+
+func pageTimelineEntries(entries []store.TimelineEntry, cursor *store.Cursor, limit int) []store.TimelineEntry {
+	page := make([]store.TimelineEntry, 0, limit)
+	for _, entry := range entries {
+		if cursor != nil && !timelineEntryBefore(entry, store.TimelineEntry{PostID: cursor.ID, CreatedAt: cursor.CreatedAt}) {
+			continue
+		}
+		page = append(page, entry)
+		if len(page) == limit {
+			break
+		}
+	}
+	return page
+}
+
+// mergeTimelineEntries merges two newest-first lists, dropping duplicates,
+// and keeps at most limit entries.
+func mergeTimelineEntries(a, b []store.TimelineEntry, limit int) []store.TimelineEntry {
+	merged := make([]store.TimelineEntry, 0, limit)
+	seen := make(map[string]bool, limit)
+
+	for len(merged) < limit && (len(a) > 0 || len(b) > 0) {
+		var next store.TimelineEntry
+		if len(b) == 0 || (len(a) > 0 && timelineEntryBefore(a[0], b[0])) {
+			next, a = a[0], a[1:]
+		} else {
+			next, b = b[0], b[1:]
+		}
+
+		if seen[next.PostID] {
+			continue
+		}
+		seen[next.PostID] = true
+		merged = append(merged, next)
+	}
+
+	return merged
+}
@@ -1,53 +1,244 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
 	"github.com/cprakhar/gopher-social/internal/store"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
+type UserProfile struct {
+	*store.User
+	store.FollowStats
+}
+
+type FollowListPage struct {
+	Users      []store.FollowListEntry `json:"users"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
 // GetUser godoc
 //
 //	@Summary	get a user
 //	@Schemes
-//	@Description	get a user by id
+//	@Description	get a user by id, with follower counts and whether the current user follows them
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		string	true	"user id"
-//	@Success		200	{object}	store.User
+//	@Success		200	{object}	UserProfile
 //	@Security		ApiKeyAuth
 //	@Router			/users/{id} [get]
 func (h *Handler) GetUserHandler(ctx *gin.Context) {
 	userID := ctx.Param("id")
 	user, err := h.getUser(ctx, userID)
 	if err != nil {
-		switch err {
-		case store.ErrNotFound:
+		switch {
+		case errors.Is(err, store.ErrNotFound):
 			h.notFoundErr(ctx, err)
-			return
 		default:
 			h.internalServerErr(ctx, err)
 		}
 		return
 	}
 
+	viewer := userFromCtx(ctx)
+
+	stats, err := h.Store.Followers.GetStats(ctx, user.ID, viewer.ID)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	visible, err := h.canView(ctx, viewer, user.ID)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+	if !visible {
+		// private accounts only show who they are until the follow is approved
+		user = &store.User{
+			ID:        user.ID,
+			Username:  user.Username,
+			CreatedAt: user.CreatedAt,
+			Private:   user.Private,
+		}
+	}
+
+	writeJSON(ctx, http.StatusOK, UserProfile{User: user, FollowStats: *stats})
+}
+
+type UpdateUserPayload struct {
+	Private *bool `json:"private" binding:"required"`
+}
+
+// UpdateUser godoc
+//
+//	@Summary	update a user
+//	@Schemes
+//	@Description	update the current user's settings; making an account public approves its pending follow requests
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string				true	"user id"
+//	@Param			payload	body		UpdateUserPayload	true	"user settings"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id} [patch]
+func (h *Handler) UpdateUserHandler(ctx *gin.Context) {
+	user := userFromCtx(ctx)
+	if ctx.Param("id") != user.ID {
+		h.forbiddenErr(ctx)
+		return
+	}
+
+	var payload UpdateUserPayload
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	approved, err := h.Store.Users.SetPrivate(ctx, user.ID, *payload.Private)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+	user.Private = *payload.Private
+
+	if h.Cfg.Redis.Enabled {
+		if err := h.CacheStorage.Users.Delete(ctx, user.ID); err != nil {
+			h.Logger.Errorw("error invalidating cached user", "user_id", user.ID, "error", err)
+		}
+		for _, followerID := range approved {
+			if err := h.backfillTimeline(ctx, followerID, user.ID); err != nil {
+				h.Logger.Errorw("error backfilling timeline", "user_id", followerID, "following_id", user.ID, "error", err)
+			}
+		}
+	}
+
 	writeJSON(ctx, http.StatusOK, user)
 }
 
+// ListFollowers godoc
+//
+//	@Summary	list followers
+//	@Schemes
+//	@Description	list the users following a user, most recent first, with cursor pagination
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"user id"
+//	@Param			limit	query		int		false	"number of users to return"	default(20)
+//	@Param			cursor	query		string	false	"cursor returned by the previous page"
+//	@Success		200		{object}	FollowListPage
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/followers [get]
+func (h *Handler) ListFollowersHandler(ctx *gin.Context) {
+	h.listFollows(ctx, h.Store.Followers.ListFollowers)
+}
+
+// ListFollowing godoc
+//
+//	@Summary	list followed users
+//	@Schemes
+//	@Description	list the users a user follows, most recent first, with cursor pagination
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"user id"
+//	@Param			limit	query		int		false	"number of users to return"	default(20)
+//	@Param			cursor	query		string	false	"cursor returned by the previous page"
+//	@Success		200		{object}	FollowListPage
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/following [get]
+func (h *Handler) ListFollowingHandler(ctx *gin.Context) {
+	h.listFollows(ctx, h.Store.Followers.ListFollowing)
+}
+
+type listFollowsFunc func(ctx context.Context, id, viewerID string, pq store.PaginatedCursorQuery) ([]store.FollowListEntry, error)
+
+func (h *Handler) listFollows(ctx *gin.Context, list listFollowsFunc) {
+	pq := store.PaginatedCursorQuery{
+		Limit: 20,
+	}
+
+	pq, err := pq.Parse(ctx, h.Cursors)
+	if err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	if err := validator.New().Struct(pq); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	user, err := h.getUser(ctx, ctx.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+		default:
+			h.internalServerErr(ctx, err)
+		}
+		return
+	}
+
+	viewer := userFromCtx(ctx)
+
+	visible, err := h.canView(ctx, viewer, user.ID)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+	if !visible {
+		h.forbiddenErr(ctx)
+		return
+	}
+
+	entries, err := list(ctx, user.ID, viewer.ID, pq)
+	if err != nil {
+		h.internalServerErr(ctx, err)
+		return
+	}
+
+	page := FollowListPage{Users: entries}
+	if len(entries) == pq.Limit {
+		last := entries[len(entries)-1]
+		page.NextCursor = h.Cursors.Encode(store.Cursor{CreatedAt: last.FollowedAt, ID: last.ID})
+	}
+
+	writeJSON(ctx, http.StatusOK, page)
+}
+
 // FollowUser godoc
 //
 //	@Summary	follow a user
 //	@Schemes
-//	@Description	follow a user by id
+//	@Description	follow a user by id; following a private account sends a follow request instead
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		string	true	"user id to follow"
 //	@Success		201	{object}	nil
+//	@Success		202	{object}	nil	"follow request sent"
 //	@Failure		400	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
 //	@Failure		409	{object}	map[string]string
 //	@Failure		500	{object}	map[string]string
 //	@Security		ApiKeyAuth
@@ -56,8 +247,23 @@ func (h *Handler) FollowUserHandler(ctx *gin.Context) {
 	user := userFromCtx(ctx)
 	followingID := ctx.Param("id")
 
-	if err := h.Store.Followers.Follow(ctx, followingID, user.ID); err != nil {
+	if err := validator.New().Var(followingID, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
+	requested, err := h.Store.Followers.Follow(ctx, followingID, user.ID)
+	if err != nil {
 		switch {
+		case errors.Is(err, store.ErrSelfFollow):
+			h.badRequestErr(ctx, err)
+			return
+		case errors.Is(err, store.ErrBlocked):
+			h.forbiddenErr(ctx)
+			return
+		case errors.Is(err, store.ErrNotFound):
+			h.notFoundErr(ctx, err)
+			return
 		case errors.Is(err, store.ErrConflict):
 			h.conflictErr(ctx, err)
 			return
@@ -67,6 +273,17 @@ func (h *Handler) FollowUserHandler(ctx *gin.Context) {
 		}
 	}
 
+	if requested {
+		ctx.Status(http.StatusAccepted)
+		return
+	}
+
+	if h.Cfg.Redis.Enabled {
+		if err := h.backfillTimeline(ctx, user.ID, followingID); err != nil {
+			h.Logger.Errorw("error backfilling timeline", "user_id", user.ID, "following_id", followingID, "error", err)
+		}
+	}
+
 	ctx.Status(http.StatusCreated)
 }
 
@@ -74,7 +291,7 @@ func (h *Handler) FollowUserHandler(ctx *gin.Context) {
 //
 //	@Summary	unfollow a user
 //	@Schemes
-//	@Description	unfollow a user by id
+//	@Description	unfollow a user by id, or withdraw a pending follow request
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
@@ -88,11 +305,22 @@ func (h *Handler) UnfollowUserHandler(ctx *gin.Context) {
 	user := userFromCtx(ctx)
 	followingID := ctx.Param("id")
 
+	if err := validator.New().Var(followingID, "uuid"); err != nil {
+		h.badRequestErr(ctx, err)
+		return
+	}
+
 	if err := h.Store.Followers.Unfollow(ctx, followingID, user.ID); err != nil {
 		h.internalServerErr(ctx, err)
 		return
 	}
 
+	if h.Cfg.Redis.Enabled {
+		if err := h.pruneTimeline(ctx, user.ID, followingID); err != nil {
+			h.Logger.Errorw("error pruning timeline", "user_id", user.ID, "following_id", followingID, "error", err)
+		}
+	}
+
 	ctx.Status(http.StatusNoContent)
 }
 
@@ -6,6 +6,8 @@ const (
 	FromName = "Gopher Social"
 	MaxRetries = 3
 	UserWelcomeTemplate = "user_invitation.tmpl"
+	AccountLockedTemplate = "account_locked.tmpl"
+	PasswordResetTemplate = "password_reset.tmpl"
 )
 
 //go:embed "templates"
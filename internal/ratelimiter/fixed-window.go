@@ -1,46 +1,47 @@
 package ratelimiter
 
 import (
-	"sync"
+	"context"
 	"time"
 )
 
+type fixedWindow struct {
+	start time.Time
+	count int
+}
+
+// FixedWindowRateLimiter allows Limit requests per client in windows that
+// start with the client's first request.
 type FixedWindowRateLimiter struct {
-	sync.RWMutex
-	clients map[string]int
-	limit   int
-	window  time.Duration
+	windows *memoryStore[fixedWindow]
 }
 
-func NewFixedWindowLimiter(limit int, window time.Duration) *FixedWindowRateLimiter {
-	return &FixedWindowRateLimiter{
-		clients: make(map[string]int),
-		limit:   limit,
-		window:  window,
-	}
+func NewFixedWindowLimiter(maxClients int) *FixedWindowRateLimiter {
+	return &FixedWindowRateLimiter{windows: newMemoryStore[fixedWindow](maxClients)}
 }
 
-func (rl *FixedWindowRateLimiter) Allow(ip string) (bool, time.Duration) {
-	rl.RLock()
-	counts, exists := rl.clients[ip]
-	rl.RUnlock()
+func (rl *FixedWindowRateLimiter) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	now := time.Now()
 
-	if !exists || counts < rl.limit {
-		rl.Lock()
-		if !exists {
-			go rl.resetCount(ip)
+	res := Result{Limit: p.Limit}
+	rl.windows.update(p.Name+":"+key, func(w *fixedWindow) time.Time {
+		end := w.start.Add(p.Window)
+		if !now.Before(end) {
+			w.start, w.count = now, 0
+			end = now.Add(p.Window)
 		}
-		rl.clients[ip]++
-		rl.Unlock()
-		return true, 0
-	}
 
-	return false, rl.window
-}
+		res.Reset = end.Sub(now)
+		if w.count < p.Limit {
+			w.count++
+			res.Allowed = true
+			res.Remaining = p.Limit - w.count
+		} else {
+			res.RetryAfter = res.Reset
+		}
+
+		return end
+	})
 
-func (rl *FixedWindowRateLimiter) resetCount(ip string) {
-	time.Sleep(rl.window)
-	rl.Lock()
-	delete(rl.clients, ip)
-	rl.Unlock()
+	return res, nil
 }
@@ -0,0 +1,137 @@
+package ratelimiter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFixedWindowAllowsLimitUnderLoad(t *testing.T) {
+	const (
+		goroutines = 64
+		requests   = 50
+		limit      = 100
+	)
+
+	rl := NewFixedWindowLimiter(1000)
+	p := Policy{Name: "test", Limit: limit, Window: time.Minute}
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range requests {
+				res, err := rl.Allow(context.Background(), "client", p)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if res.Allowed {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != limit {
+		t.Errorf("allowed %d requests, want %d", got, limit)
+	}
+}
+
+func TestFixedWindowRetryAfterIsTimeLeftInWindow(t *testing.T) {
+	const elapsed = 100 * time.Millisecond
+
+	rl := NewFixedWindowLimiter(10)
+	p := Policy{Name: "test", Limit: 1, Window: time.Second}
+
+	if res, _ := rl.Allow(context.Background(), "client", p); !res.Allowed {
+		t.Fatal("first request was denied")
+	}
+
+	time.Sleep(elapsed)
+
+	res, _ := rl.Allow(context.Background(), "client", p)
+	if res.Allowed {
+		t.Fatal("request over the limit was allowed")
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > p.Window-elapsed {
+		t.Errorf("RetryAfter = %v, want at most %v", res.RetryAfter, p.Window-elapsed)
+	}
+	if res.RetryAfter != res.Reset {
+		t.Errorf("RetryAfter = %v, want it to match Reset %v", res.RetryAfter, res.Reset)
+	}
+}
+
+func TestFixedWindowRestartsAfterWindow(t *testing.T) {
+	rl := NewFixedWindowLimiter(10)
+	p := Policy{Name: "test", Limit: 1, Window: 50 * time.Millisecond}
+
+	rl.Allow(context.Background(), "client", p)
+	if res, _ := rl.Allow(context.Background(), "client", p); res.Allowed {
+		t.Fatal("request over the limit was allowed")
+	}
+
+	time.Sleep(p.Window)
+
+	res, _ := rl.Allow(context.Background(), "client", p)
+	if !res.Allowed {
+		t.Fatal("request in a new window was denied")
+	}
+	if res.Reset <= 0 || res.Reset > p.Window {
+		t.Errorf("Reset = %v, want a full window of at most %v", res.Reset, p.Window)
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	m := newMemoryStore[int](3)
+	touch := func(key string) {
+		m.update(key, func(n *int) time.Time {
+			*n++
+			return time.Now().Add(time.Hour)
+		})
+	}
+
+	touch("a")
+	touch("b")
+	touch("c")
+	// a becomes the most recently used, leaving b the oldest
+	touch("a")
+	touch("d")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.entries) != 3 || m.lru.Len() != 3 {
+		t.Fatalf("store holds %d entries, want 3", len(m.entries))
+	}
+	if _, ok := m.entries["b"]; ok {
+		t.Error("least recently used key b was not evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := m.entries[key]; !ok {
+			t.Errorf("key %s was evicted", key)
+		}
+	}
+	if n := m.entries["a"].Value.(*memoryEntry[int]).state; n != 2 {
+		t.Errorf("state of a = %d, want 2", n)
+	}
+}
+
+func BenchmarkFixedWindowAllow(b *testing.B) {
+	rl := NewFixedWindowLimiter(10000)
+	p := Policy{Name: "bench", Limit: 1 << 30, Window: time.Minute}
+
+	var next atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		key := "client-" + strconv.FormatInt(next.Add(1)%100, 10)
+		for pb.Next() {
+			rl.Allow(context.Background(), key, p)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package ratelimiter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+var ErrUnlockTokenNotFound = errors.New("unlock token not found")
+
+// LockoutPolicy locks a key out once it has Threshold failures within
+// Window. The lockout starts at BaseDelay and doubles with every further
+// failure, up to MaxDelay.
+type LockoutPolicy struct {
+	Name      string
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Window    time.Duration
+}
+
+// delay is how long a key with the given number of failures is locked out.
+func (p LockoutPolicy) delay(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(failures-p.Threshold))
+	return time.Duration(math.Min(delay, float64(p.MaxDelay)))
+}
+
+// Lockout tracks failed attempts, such as logins, per key.
+type Lockout interface {
+	// Check returns how long the key is still locked out, or zero.
+	Check(ctx context.Context, key string) (time.Duration, error)
+	// Fail records a failed attempt and returns the number of failures in
+	// the window and how long the key is now locked out.
+	Fail(ctx context.Context, key string) (int, time.Duration, error)
+	// Reset forgets the failures of the key.
+	Reset(ctx context.Context, key string) error
+	// IssueUnlock returns a single-use token that resets the key through
+	// Unlock. It expires with the failures of the key.
+	IssueUnlock(ctx context.Context, key string) (string, error)
+	// Unlock resets the key the token was issued for.
+	Unlock(ctx context.Context, token string) error
+}
+
+func NewLockout(backend string, rdb *redis.Client, maxKeys int, p LockoutPolicy) Lockout {
+	if backend == "redis" {
+		return &RedisLockout{rdb: rdb, policy: p}
+	}
+	return NewMemoryLockout(maxKeys, p)
+}
+
+type lockoutState struct {
+	failures    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// MemoryLockout tracks failures in the memory of this process.
+type MemoryLockout struct {
+	policy  LockoutPolicy
+	keys    *memoryStore[lockoutState]
+	unlocks *memoryStore[string]
+}
+
+func NewMemoryLockout(maxKeys int, p LockoutPolicy) *MemoryLockout {
+	return &MemoryLockout{
+		policy:  p,
+		keys:    newMemoryStore[lockoutState](maxKeys),
+		unlocks: newMemoryStore[string](maxKeys),
+	}
+}
+
+func (l *MemoryLockout) Check(ctx context.Context, key string) (time.Duration, error) {
+	// checked keys are looked up rather than updated, so logins for unknown
+	// accounts cannot push the counters of attacked ones out of the store
+	s, ok := l.keys.lookup(key)
+	if !ok {
+		return 0, nil
+	}
+
+	return max(time.Until(s.lockedUntil), 0), nil
+}
+
+func (l *MemoryLockout) Fail(ctx context.Context, key string) (int, time.Duration, error) {
+	now := time.Now()
+
+	var failures int
+	var delay time.Duration
+	l.keys.update(key, func(s *lockoutState) time.Time {
+		l.expire(s, now)
+		s.failures++
+		s.lastFailure = now
+
+		failures = s.failures
+		delay = l.policy.delay(s.failures)
+		if delay > 0 {
+			s.lockedUntil = now.Add(delay)
+		}
+		return now.Add(max(l.policy.Window, delay))
+	})
+
+	return failures, delay, nil
+}
+
+func (l *MemoryLockout) Reset(ctx context.Context, key string) error {
+	l.keys.update(key, func(s *lockoutState) time.Time {
+		*s = lockoutState{}
+		return time.Time{}
+	})
+	return nil
+}
+
+func (l *MemoryLockout) IssueUnlock(ctx context.Context, key string) (string, error) {
+	token := uuid.NewString()
+	l.unlocks.update(hashUnlockToken(token), func(k *string) time.Time {
+		*k = key
+		return time.Now().Add(l.policy.Window)
+	})
+	return token, nil
+}
+
+func (l *MemoryLockout) Unlock(ctx context.Context, token string) error {
+	now := time.Now()
+
+	var key string
+	l.unlocks.update(hashUnlockToken(token), func(k *string) time.Time {
+		key, *k = *k, ""
+		return now
+	})
+	if key == "" {
+		return ErrUnlockTokenNotFound
+	}
+
+	return l.Reset(ctx, key)
+}
+
+// expire forgets failures older than the window once any lockout is over.
+func (l *MemoryLockout) expire(s *lockoutState, now time.Time) {
+	if now.After(s.lastFailure.Add(l.policy.Window)) && !now.Before(s.lockedUntil) {
+		*s = lockoutState{}
+	}
+}
+
+// lockoutFailScript counts a failure and locks the key out once the
+// threshold is reached. KEYS are the failure counter and the lock, ARGV the
+// threshold, base delay, max delay and window, durations in milliseconds.
+var lockoutFailScript = redis.NewScript(`
+local threshold = tonumber(ARGV[1])
+local base = tonumber(ARGV[2])
+local max = tonumber(ARGV[3])
+local window = tonumber(ARGV[4])
+
+local failures = redis.call("INCR", KEYS[1])
+local delay = 0
+if failures >= threshold then
+	delay = math.min(base * 2 ^ (failures - threshold), max)
+	redis.call("SET", KEYS[2], 1, "PX", math.ceil(delay))
+end
+
+redis.call("PEXPIRE", KEYS[1], math.max(window, math.ceil(delay)))
+return {failures, math.ceil(delay)}
+`)
+
+// RedisLockout tracks failures in Redis, so they are shared by every API
+// instance.
+type RedisLockout struct {
+	rdb    *redis.Client
+	policy LockoutPolicy
+}
+
+func (l *RedisLockout) Check(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := l.rdb.PTTL(ctx, l.lockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// PTTL reports missing keys with a negative duration
+	return max(ttl, 0), nil
+}
+
+func (l *RedisLockout) Fail(ctx context.Context, key string) (int, time.Duration, error) {
+	res, err := lockoutFailScript.Run(ctx, l.rdb, []string{l.failuresKey(key), l.lockKey(key)},
+		l.policy.Threshold, l.policy.BaseDelay.Milliseconds(), l.policy.MaxDelay.Milliseconds(), l.policy.Window.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int(res[0]), time.Duration(res[1]) * time.Millisecond, nil
+}
+
+func (l *RedisLockout) Reset(ctx context.Context, key string) error {
+	return l.rdb.Del(ctx, l.failuresKey(key), l.lockKey(key)).Err()
+}
+
+func (l *RedisLockout) IssueUnlock(ctx context.Context, key string) (string, error) {
+	token := uuid.NewString()
+	if err := l.rdb.Set(ctx, l.unlockKey(token), key, l.policy.Window).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (l *RedisLockout) Unlock(ctx context.Context, token string) error {
+	key, err := l.rdb.GetDel(ctx, l.unlockKey(token)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrUnlockTokenNotFound
+		}
+		return err
+	}
+
+	return l.Reset(ctx, key)
+}
+
+func (l *RedisLockout) failuresKey(key string) string {
+	return "lockout:" + l.policy.Name + ":failures:" + key
+}
+
+func (l *RedisLockout) lockKey(key string) string {
+	return "lockout:" + l.policy.Name + ":locked:" + key
+}
+
+func (l *RedisLockout) unlockKey(token string) string {
+	return "lockout:" + l.policy.Name + ":unlock:" + hashUnlockToken(token)
+}
+
+// hashUnlockToken keeps plain unlock tokens out of storage.
+func hashUnlockToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
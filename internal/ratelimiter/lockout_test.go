@@ -0,0 +1,105 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryLockoutThreshold(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLockout(10, LockoutPolicy{Name: "test", Threshold: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+
+	for i := 1; i < 3; i++ {
+		failures, delay, _ := l.Fail(ctx, "key")
+		if failures != i || delay != 0 {
+			t.Fatalf("failure %d: got %d failures and a %v lockout, want no lockout", i, failures, delay)
+		}
+		if locked, _ := l.Check(ctx, "key"); locked != 0 {
+			t.Fatalf("failure %d: locked for %v below the threshold", i, locked)
+		}
+	}
+
+	failures, delay, _ := l.Fail(ctx, "key")
+	if failures != 3 || delay != time.Minute {
+		t.Fatalf("got %d failures and a %v lockout, want 3 and 1m", failures, delay)
+	}
+	if locked, _ := l.Check(ctx, "key"); locked <= 0 || locked > time.Minute {
+		t.Errorf("Check = %v, want a lockout of at most 1m", locked)
+	}
+}
+
+func TestMemoryLockoutBackoffIsCapped(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLockout(10, LockoutPolicy{Name: "test", Threshold: 1, BaseDelay: time.Second, MaxDelay: 4 * time.Second, Window: time.Hour})
+
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if _, delay, _ := l.Fail(ctx, "key"); delay != want {
+			t.Errorf("failure %d: lockout = %v, want %v", i+1, delay, want)
+		}
+	}
+}
+
+func TestMemoryLockoutFailuresExpireAfterWindow(t *testing.T) {
+	ctx := context.Background()
+	p := LockoutPolicy{Name: "test", Threshold: 2, BaseDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond, Window: 50 * time.Millisecond}
+	l := NewMemoryLockout(10, p)
+
+	l.Fail(ctx, "key")
+	time.Sleep(p.Window + 10*time.Millisecond)
+
+	failures, delay, _ := l.Fail(ctx, "key")
+	if failures != 1 || delay != 0 {
+		t.Errorf("got %d failures and a %v lockout after the window, want 1 and none", failures, delay)
+	}
+}
+
+func TestMemoryLockoutUnlockTokenIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLockout(10, LockoutPolicy{Name: "test", Threshold: 1, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+
+	l.Fail(ctx, "key")
+	token, err := l.IssueUnlock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Unlock(ctx, token); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if locked, _ := l.Check(ctx, "key"); locked != 0 {
+		t.Errorf("still locked for %v after unlocking", locked)
+	}
+
+	if err := l.Unlock(ctx, token); !errors.Is(err, ErrUnlockTokenNotFound) {
+		t.Errorf("second Unlock = %v, want ErrUnlockTokenNotFound", err)
+	}
+	if err := l.Unlock(ctx, "unknown"); !errors.Is(err, ErrUnlockTokenNotFound) {
+		t.Errorf("Unlock with an unknown token = %v, want ErrUnlockTokenNotFound", err)
+	}
+}
+
+func TestMemoryLockoutCheckDoesNotTrackKeys(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLockout(2, LockoutPolicy{Name: "test", Threshold: 1, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+
+	l.Fail(ctx, "victim")
+
+	// logins with many unknown emails must not evict the locked account
+	for i := range 100 {
+		l.Check(ctx, "attacker-"+strconv.Itoa(i))
+	}
+
+	l.keys.mu.Lock()
+	tracked := len(l.keys.entries)
+	l.keys.mu.Unlock()
+	if tracked != 1 {
+		t.Errorf("store tracks %d keys, want only the failed one", tracked)
+	}
+
+	if locked, _ := l.Check(ctx, "victim"); locked <= 0 {
+		t.Error("victim is no longer locked out")
+	}
+}
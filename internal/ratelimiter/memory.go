@@ -0,0 +1,95 @@
+package ratelimiter
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often memory limiters drop clients whose state has
+// expired.
+const sweepInterval = time.Minute
+
+type memoryEntry[T any] struct {
+	key     string
+	state   T
+	expires time.Time
+}
+
+// memoryStore holds per-client limiter state in memory. A single janitor
+// goroutine removes entries once they expire, and at most maxEntries are
+// kept: when full, the least recently used client is forgotten.
+type memoryStore[T any] struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+	maxEntries int
+}
+
+func newMemoryStore[T any](maxEntries int) *memoryStore[T] {
+	m := &memoryStore[T]{
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+	}
+	go m.sweep()
+	return m
+}
+
+// update calls fn with the state of key, a zero state if it has none, while
+// holding the lock. fn returns when the state can be forgotten.
+func (m *memoryStore[T]) update(key string, fn func(state *T) time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if ok {
+		m.lru.MoveToFront(el)
+	} else {
+		if m.lru.Len() >= m.maxEntries {
+			m.remove(m.lru.Back())
+		}
+		el = m.lru.PushFront(&memoryEntry[T]{key: key})
+		m.entries[key] = el
+	}
+
+	e := el.Value.(*memoryEntry[T])
+	e.expires = fn(&e.state)
+}
+
+// lookup returns a copy of the state of key. Unlike update it never adds the
+// key, so looking up unknown clients cannot evict tracked ones.
+func (m *memoryStore[T]) lookup(key string) (T, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+
+	return el.Value.(*memoryEntry[T]).state, true
+}
+
+func (m *memoryStore[T]) remove(el *list.Element) {
+	m.lru.Remove(el)
+	delete(m.entries, el.Value.(*memoryEntry[T]).key)
+}
+
+func (m *memoryStore[T]) sweep() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.mu.Lock()
+		for el := m.lru.Back(); el != nil; {
+			prev := el.Prev()
+			if now.After(el.Value.(*memoryEntry[T]).expires) {
+				m.remove(el)
+			}
+			el = prev
+		}
+		m.mu.Unlock()
+	}
+}
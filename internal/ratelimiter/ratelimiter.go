@@ -1,13 +1,142 @@
 package ratelimiter
 
-import "time"
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	FixedWindow   = "fixed-window"
+	TokenBucket   = "token-bucket"
+	SlidingWindow = "sliding-window"
+)
+
+// Limiter decides whether the client identified by key may make another
+// request under the policy.
 type Limiter interface {
-	Allow(ip string) (bool, time.Duration)
+	Allow(ctx context.Context, key string, p Policy) (Result, error)
+}
+
+// Policy allows Limit requests per Window. Clients are counted separately
+// for each policy name.
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is roughly how long until the client's quota is restored; how
+	// precise it is depends on the algorithm.
+	Reset time.Duration
+	// RetryAfter is how long a denied client has to wait.
+	RetryAfter time.Duration
 }
 
 type Config struct {
-	RequestsPerTimeFrame int
-	TimeFrame            time.Duration
-	Enabled              bool
+	Enabled bool
+	// Backend is "memory" for a per-process limiter or "redis" to share
+	// limits across every API instance.
+	Backend   string
+	Algorithm string
+	// MaxClients bounds how many clients the memory backend tracks; the
+	// least recently seen are forgotten first.
+	MaxClients int
+	// Default applies to every route without a policy of its own.
+	Default Policy
+	// Routes maps "METHOD /route/pattern" to the policy of that route.
+	Routes map[string]Policy
+}
+
+// PolicyFor returns the policy of the route, or the default policy.
+func (c Config) PolicyFor(method, route string) Policy {
+	if p, ok := c.Routes[method+" "+route]; ok {
+		return p
+	}
+	return c.Default
+}
+
+// ParseRoutes reads route policies written as "METHOD /route/pattern=limit/window"
+// and separated by semicolons, for example
+// "POST /v1/authenticate/token=10/1m; GET /v1/search=30/1m". Each policy is
+// named after its route.
+func ParseRoutes(spec string) (map[string]Policy, error) {
+	routes := map[string]Policy{}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, policy, ok := strings.Cut(entry, "=")
+		limit, window, ok2 := strings.Cut(policy, "/")
+		method, pattern, ok3 := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !ok2 || !ok3 || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("rate limit route %q is not of the form METHOD /route=limit/window", entry)
+		}
+
+		l, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil {
+			return nil, fmt.Errorf("rate limit route %q: invalid limit: %w", entry, err)
+		}
+		w, err := time.ParseDuration(strings.TrimSpace(window))
+		if err != nil {
+			return nil, fmt.Errorf("rate limit route %q: invalid window: %w", entry, err)
+		}
+
+		key := strings.ToUpper(method) + " " + strings.TrimSpace(pattern)
+		routes[key] = Policy{Name: key, Limit: l, Window: w}
+	}
+
+	return routes, nil
+}
+
+// New returns the limiter for the configured backend and algorithm. rdb is
+// only used by the redis backend.
+func New(cfg Config, rdb *redis.Client) (Limiter, error) {
+	for _, p := range cfg.policies() {
+		if p.Limit < 1 || p.Window <= 0 {
+			return nil, fmt.Errorf("rate limit policy %q needs a positive limit and window", p.Name)
+		}
+	}
+
+	switch cfg.Backend {
+	case "memory":
+		if cfg.MaxClients < 1 {
+			return nil, fmt.Errorf("the memory rate limiter backend needs a positive client limit")
+		}
+		switch cfg.Algorithm {
+		case FixedWindow:
+			return NewFixedWindowLimiter(cfg.MaxClients), nil
+		case TokenBucket:
+			return NewTokenBucketLimiter(cfg.MaxClients), nil
+		case SlidingWindow:
+			return NewSlidingWindowLimiter(cfg.MaxClients), nil
+		}
+	case "redis":
+		if rdb == nil {
+			return nil, fmt.Errorf("the redis rate limiter backend requires redis to be enabled")
+		}
+		return NewRedisLimiter(rdb, cfg.Algorithm)
+	default:
+		return nil, fmt.Errorf("unknown rate limiter backend %q", cfg.Backend)
+	}
+
+	return nil, fmt.Errorf("unknown rate limiter algorithm %q", cfg.Algorithm)
+}
+
+func (c Config) policies() []Policy {
+	policies := []Policy{c.Default}
+	for _, p := range c.Routes {
+		policies = append(policies, p)
+	}
+	return policies
 }
@@ -0,0 +1,37 @@
+package ratelimiter
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestParseRoutes(t *testing.T) {
+	got, err := ParseRoutes(" POST /v1/authenticate/token=5/1m; get /v1/search=60/30s; ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]Policy{
+		"POST /v1/authenticate/token": {Name: "POST /v1/authenticate/token", Limit: 5, Window: time.Minute},
+		"GET /v1/search":              {Name: "GET /v1/search", Limit: 60, Window: 30 * time.Second},
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("ParseRoutes() = %v, want %v", got, want)
+	}
+}
+
+func TestParseRoutesRejectsMalformedEntries(t *testing.T) {
+	for _, spec := range []string{
+		"/v1/search=60/1m",
+		"GET v1/search=60/1m",
+		"GET /v1/search",
+		"GET /v1/search=60",
+		"GET /v1/search=many/1m",
+		"GET /v1/search=60/soon",
+	} {
+		if _, err := ParseRoutes(spec); err == nil {
+			t.Errorf("ParseRoutes(%q) succeeded, want an error", spec)
+		}
+	}
+}
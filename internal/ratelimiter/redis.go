@@ -0,0 +1,128 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The scripts below use Redis' clock so every instance agrees on the time.
+// They take the limit and the window in microseconds as ARGV, and return
+// {allowed, remaining, reset, retry after}, durations in microseconds.
+const redisNow = `
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+`
+
+var fixedWindowScript = redis.NewScript(redisNow + `
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], math.ceil(window / 1000))
+end
+
+local reset = redis.call("PTTL", KEYS[1]) * 1000
+if count > limit then
+	return {0, 0, reset, reset}
+end
+return {1, limit - count, reset, 0}
+`)
+
+// tokenBucketScript implements the generic cell rate algorithm, which
+// behaves like a token bucket but only stores the theoretical arrival time
+// (TAT) of the next request.
+var tokenBucketScript = redis.NewScript(redisNow + `
+local interval = window / limit
+
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+
+local next_tat = tat + interval
+local allow_at = next_tat - window
+if allow_at > now then
+	return {0, 0, tat - now, allow_at - now}
+end
+
+redis.call("SET", KEYS[1], next_tat, "PX", math.ceil((next_tat - now) / 1000))
+return {1, math.floor((window - (next_tat - now)) / interval), next_tat - now, 0}
+`)
+
+// slidingWindowScript mirrors SlidingWindowLimiter; the counts of the
+// current and previous window are kept in a hash.
+var slidingWindowScript = redis.NewScript(redisNow + `
+local index = math.floor(now / window)
+local elapsed = now - index * window
+
+local data = redis.call("HMGET", KEYS[1], "index", "curr", "prev")
+local stored = tonumber(data[1])
+local curr = tonumber(data[2]) or 0
+local prev = tonumber(data[3]) or 0
+if stored == index - 1 then
+	prev, curr = curr, 0
+elseif stored ~= index then
+	prev, curr = 0, 0
+end
+
+local reset = window - elapsed
+local estimate = prev * (window - elapsed) / window + curr
+if estimate + 1 > limit then
+	local allowed = limit - 1
+	local wait
+	if curr <= allowed then
+		wait = math.max(window * (1 - (allowed - curr) / prev) - elapsed, 0)
+	else
+		wait = window - elapsed
+		if curr > 0 then
+			wait = wait + window * math.max(1 - allowed / curr, 0)
+		end
+	end
+	return {0, 0, reset, math.ceil(wait)}
+end
+
+redis.call("HSET", KEYS[1], "index", index, "curr", curr + 1, "prev", prev)
+redis.call("PEXPIRE", KEYS[1], math.ceil((2 * window - elapsed) / 1000))
+return {1, math.floor(limit - estimate - 1), reset, 0}
+`)
+
+var redisScripts = map[string]*redis.Script{
+	FixedWindow:   fixedWindowScript,
+	TokenBucket:   tokenBucketScript,
+	SlidingWindow: slidingWindowScript,
+}
+
+// RedisLimiter keeps its state in Redis, so limits hold across every API
+// instance. Each decision is a single atomic script call.
+type RedisLimiter struct {
+	rdb    *redis.Client
+	script *redis.Script
+}
+
+func NewRedisLimiter(rdb *redis.Client, algorithm string) (*RedisLimiter, error) {
+	script, ok := redisScripts[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown rate limiter algorithm %q", algorithm)
+	}
+
+	return &RedisLimiter{rdb: rdb, script: script}, nil
+}
+
+func (rl *RedisLimiter) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	keys := []string{"ratelimit:" + p.Name + ":" + key}
+	res, err := rl.script.Run(ctx, rl.rdb, keys, p.Limit, p.Window.Microseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    res[0] == 1,
+		Limit:      p.Limit,
+		Remaining:  int(res[1]),
+		Reset:      time.Duration(res[2]) * time.Microsecond,
+		RetryAfter: time.Duration(res[3]) * time.Microsecond,
+	}, nil
+}
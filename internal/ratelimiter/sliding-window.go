@@ -0,0 +1,81 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+type slidingWindow struct {
+	index int64
+	curr  int
+	prev  int
+}
+
+// SlidingWindowLimiter counts requests in fixed windows but weighs the
+// previous window by how much of it still overlaps the sliding window, so
+// clients cannot make twice the limit around a window boundary.
+type SlidingWindowLimiter struct {
+	windows *memoryStore[slidingWindow]
+}
+
+func NewSlidingWindowLimiter(maxClients int) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{windows: newMemoryStore[slidingWindow](maxClients)}
+}
+
+func (rl *SlidingWindowLimiter) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	now := time.Now()
+	index := now.UnixNano() / int64(p.Window)
+	elapsed := time.Duration(now.UnixNano() - index*int64(p.Window))
+
+	res := Result{Limit: p.Limit, Reset: p.Window - elapsed}
+	rl.windows.update(p.Name+":"+key, func(w *slidingWindow) time.Time {
+		switch w.index {
+		case index:
+		case index - 1:
+			w.prev, w.curr = w.curr, 0
+		default:
+			w.prev, w.curr = 0, 0
+		}
+		w.index = index
+
+		estimate := slidingEstimate(w.prev, w.curr, elapsed, p.Window)
+		if estimate+1 > float64(p.Limit) {
+			res.RetryAfter = slidingRetryAfter(w.prev, w.curr, p.Limit, elapsed, p.Window)
+		} else {
+			w.curr++
+			res.Allowed = true
+			res.Remaining = int(float64(p.Limit) - estimate - 1)
+		}
+
+		// the current count is still needed as the previous window
+		return now.Add(2*p.Window - elapsed)
+	})
+
+	return res, nil
+}
+
+// slidingEstimate is the number of requests in the sliding window ending
+// elapsed into the current window.
+func slidingEstimate(prev, curr int, elapsed, window time.Duration) float64 {
+	return float64(prev)*float64(window-elapsed)/float64(window) + float64(curr)
+}
+
+// slidingRetryAfter is how long until the estimate drops enough to allow
+// another request.
+func slidingRetryAfter(prev, curr, limit int, elapsed, window time.Duration) time.Duration {
+	allowed := float64(limit - 1)
+
+	if float64(curr) <= allowed {
+		// wait for enough of the previous window to slide out
+		wait := float64(window)*(1-(allowed-float64(curr))/float64(prev)) - float64(elapsed)
+		return time.Duration(math.Max(wait, 0))
+	}
+
+	// wait for the next window, then for enough of this one to slide out
+	wait := float64(window - elapsed)
+	if curr > 0 {
+		wait += float64(window) * math.Max(1-allowed/float64(curr), 0)
+	}
+	return time.Duration(wait)
+}
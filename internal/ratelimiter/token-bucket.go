@@ -0,0 +1,52 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+)
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// TokenBucketLimiter holds a bucket of Limit tokens per client, refilled
+// evenly over the window. Each request takes a token, so clients can burst
+// up to the limit and then proceed at the refill rate.
+type TokenBucketLimiter struct {
+	buckets *memoryStore[tokenBucket]
+}
+
+func NewTokenBucketLimiter(maxClients int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{buckets: newMemoryStore[tokenBucket](maxClients)}
+}
+
+func (rl *TokenBucketLimiter) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	now := time.Now()
+	limit := float64(p.Limit)
+	// time it takes to refill one token
+	interval := p.Window / time.Duration(p.Limit)
+
+	res := Result{Limit: p.Limit}
+	rl.buckets.update(p.Name+":"+key, func(b *tokenBucket) time.Time {
+		if b.updated.IsZero() {
+			b.tokens = limit
+		} else {
+			b.tokens = min(limit, b.tokens+float64(now.Sub(b.updated))/float64(interval))
+		}
+		b.updated = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			res.Allowed = true
+		} else {
+			res.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+		}
+
+		res.Remaining = int(b.tokens)
+		res.Reset = time.Duration((limit - b.tokens) * float64(interval))
+		return now.Add(res.Reset)
+	})
+
+	return res, nil
+}
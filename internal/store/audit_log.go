@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditEntry records an action taken by a moderator or administrator.
+type AuditEntry struct {
+	ID         string    `json:"id"`
+	ActorID    *string   `json:"actor_id"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id"`
+	ReportID   *string   `json:"report_id"`
+	Details    string    `json:"details"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AuditLogStore struct {
+	db *pgxpool.Pool
+}
+
+// List returns a page of the audit log, most recent first.
+func (a *AuditLogStore) List(ctx context.Context, pq PaginatedCursorQuery) ([]AuditEntry, error) {
+	query := `
+		SELECT id, actor_id, action, target_type, target_id, report_id, details, created_at
+		FROM audit_log
+		WHERE $1::timestamptz IS NULL OR (created_at, id) < ($1, $2::uuid)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	createdAt, id := pq.Cursor.values()
+	rows, err := a.db.Query(ctx, query, createdAt, id, pq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &e.ReportID, &e.Details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// recordAudit appends an entry to the audit log inside the transaction that
+// performs the action, so an action is never committed without its record.
+func recordAudit(ctx context.Context, tx pgx.Tx, entry *AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, report_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return tx.QueryRow(ctx, query, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, entry.ReportID, entry.Details).
+		Scan(&entry.ID, &entry.CreatedAt)
+}
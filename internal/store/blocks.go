@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrSelfBlock = errors.New("users cannot block themselves")
+	ErrBlocked   = errors.New("user is blocked")
+)
+
+type BlocksStore struct {
+	db *pgxpool.Pool
+}
+
+// Block makes id block blockedID and removes any follows and follow requests
+// between the two in either direction. Blocking twice is not an error.
+func (b *BlocksStore) Block(ctx context.Context, id, blockedID string) error {
+	if id == blockedID {
+		return ErrSelfBlock
+	}
+
+	return withTx(b.db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_blocks (user_id, blocked_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, id, blockedID)
+		if err != nil {
+			return mapPgError(err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			DELETE FROM followers
+			WHERE (user_id = $1 AND following_id = $2) OR (user_id = $2 AND following_id = $1)
+		`, id, blockedID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			DELETE FROM follow_requests
+			WHERE (user_id = $1 AND target_id = $2) OR (user_id = $2 AND target_id = $1)
+		`, id, blockedID)
+		return err
+	})
+}
+
+// Unblock lifts the block from id on blockedID. Follows removed by the block
+// are not restored.
+func (b *BlocksStore) Unblock(ctx context.Context, id, blockedID string) error {
+	query := `
+		DELETE FROM user_blocks
+		WHERE user_id = $1 AND blocked_id = $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := b.db.Exec(ctx, query, id, blockedID)
+	return err
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func (b *BlocksStore) IsBlocked(ctx context.Context, id, otherID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)
+		)
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var blocked bool
+	if err := b.db.QueryRow(ctx, query, id, otherID).Scan(&blocked); err != nil {
+		return false, err
+	}
+
+	return blocked, nil
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/cprakhar/gopher-social/internal/store"
 	"github.com/go-redis/redis/v8"
@@ -11,11 +12,28 @@ type Store struct {
 	Users interface {
 		Get(context.Context, string) (*store.User, error)
 		Set(context.Context, *store.User) error
+		Delete(context.Context, string) error
+	}
+	Tokens interface {
+		Revoke(context.Context, string, time.Duration) error
+		IsRevoked(context.Context, string) (bool, error)
+	}
+	Timelines interface {
+		Push(context.Context, []string, store.TimelineEntry, int) error
+		Build(context.Context, string, []store.TimelineEntry) error
+		Backfill(context.Context, string, []store.TimelineEntry, int) error
+		Remove(context.Context, string, []string) error
+		Range(context.Context, string, *store.Cursor, int) ([]store.TimelineEntry, bool, error)
+		SetCelebrity(context.Context, string, bool) error
+		IsCelebrity(context.Context, string) (bool, error)
+		Celebrities(context.Context) ([]string, error)
 	}
 }
 
 func NewRedisStore(rdb *redis.Client) Store {
 	return Store{
-		Users: &UserStore{rdb},
+		Users:     &UserStore{rdb},
+		Tokens:    &TokenStore{rdb},
+		Timelines: &TimelineStore{rdb},
 	}
 }
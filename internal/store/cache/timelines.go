@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cprakhar/gopher-social/internal/store"
+	"github.com/go-redis/redis/v8"
+)
+
+type TimelineStore struct {
+	rdb *redis.Client
+}
+
+// TimelineTimeExp bounds how long an unread timeline is kept; it is rebuilt
+// from Postgres on the next read.
+const TimelineTimeExp = 7 * 24 * time.Hour
+
+const celebritiesKey = "timeline:celebrities"
+
+// pushScript adds a post to a timeline and trims it, but only if the timeline
+// has already been built. Creating a partial timeline would hide older posts.
+var pushScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[1], ARGV[2])
+redis.call("ZREMRANGEBYRANK", KEYS[1], 0, -(tonumber(ARGV[3]) + 1))
+return 1
+`)
+
+func timelineKey(userID string) string {
+	return "timeline:" + userID
+}
+
+func timelineScore(t time.Time) float64 {
+	return float64(t.UnixMicro())
+}
+
+// Push adds the entry to the timelines of every user that has one, trimming
+// each to maxLen entries.
+func (t *TimelineStore) Push(ctx context.Context, userIDs []string, entry store.TimelineEntry, maxLen int) error {
+	pipe := t.rdb.Pipeline()
+	for _, id := range userIDs {
+		pushScript.Eval(ctx, pipe, []string{timelineKey(id)}, timelineScore(entry.CreatedAt), entry.PostID, maxLen)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Build replaces the user's timeline with the given entries.
+func (t *TimelineStore) Build(ctx context.Context, userID string, entries []store.TimelineEntry) error {
+	key := timelineKey(userID)
+
+	pipe := t.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	if len(entries) > 0 {
+		members := make([]*redis.Z, len(entries))
+		for i, entry := range entries {
+			members[i] = &redis.Z{Score: timelineScore(entry.CreatedAt), Member: entry.PostID}
+		}
+		pipe.ZAdd(ctx, key, members...)
+		pipe.Expire(ctx, key, TimelineTimeExp)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Backfill merges entries into the user's timeline if it has been built.
+func (t *TimelineStore) Backfill(ctx context.Context, userID string, entries []store.TimelineEntry, maxLen int) error {
+	pipe := t.rdb.Pipeline()
+	for _, entry := range entries {
+		pushScript.Eval(ctx, pipe, []string{timelineKey(userID)}, timelineScore(entry.CreatedAt), entry.PostID, maxLen)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (t *TimelineStore) Remove(ctx context.Context, userID string, postIDs []string) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	members := make([]any, len(postIDs))
+	for i, id := range postIDs {
+		members[i] = id
+	}
+
+	return t.rdb.ZRem(ctx, timelineKey(userID), members...).Err()
+}
+
+// Range returns up to limit entries of the user's timeline, newest first,
+// starting after the cursor when one is set. The boolean is false when the
+// timeline has not been built yet.
+func (t *TimelineStore) Range(ctx context.Context, userID string, cursor *store.Cursor, limit int) ([]store.TimelineEntry, bool, error) {
+	key := timelineKey(userID)
+
+	exists, err := t.rdb.Exists(ctx, key).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if exists == 0 {
+		return nil, false, nil
+	}
+
+	max := "+inf"
+	if cursor != nil {
+		max = strconv.FormatFloat(timelineScore(cursor.CreatedAt), 'f', -1, 64)
+	}
+
+	// over-fetch a little so entries sharing the cursor's score can be skipped
+	results, err := t.rdb.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Max:   max,
+		Min:   "-inf",
+		Count: int64(limit + 10),
+	}).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	entries := make([]store.TimelineEntry, 0, limit)
+	for _, z := range results {
+		entry := store.TimelineEntry{
+			PostID:    z.Member.(string),
+			CreatedAt: time.UnixMicro(int64(z.Score)),
+		}
+		if cursor != nil && z.Score == timelineScore(cursor.CreatedAt) && entry.PostID >= cursor.ID {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) == limit {
+			break
+		}
+	}
+
+	if err := t.rdb.Expire(ctx, key, TimelineTimeExp).Err(); err != nil {
+		return nil, false, err
+	}
+
+	return entries, true, nil
+}
+
+// SetCelebrity records whether the author's posts are fanned out on write or
+// pulled on read.
+func (t *TimelineStore) SetCelebrity(ctx context.Context, authorID string, celebrity bool) error {
+	if celebrity {
+		return t.rdb.SAdd(ctx, celebritiesKey, authorID).Err()
+	}
+	return t.rdb.SRem(ctx, celebritiesKey, authorID).Err()
+}
+
+func (t *TimelineStore) IsCelebrity(ctx context.Context, authorID string) (bool, error) {
+	return t.rdb.SIsMember(ctx, celebritiesKey, authorID).Result()
+}
+
+func (t *TimelineStore) Celebrities(ctx context.Context) ([]string, error) {
+	return t.rdb.SMembers(ctx, celebritiesKey).Result()
+}
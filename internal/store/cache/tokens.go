@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type TokenStore struct {
+	rdb *redis.Client
+}
+
+func (t *TokenStore) Revoke(ctx context.Context, id string, exp time.Duration) error {
+	cacheKey := "revoked_token:" + id
+	return t.rdb.SetEX(ctx, cacheKey, 1, exp).Err()
+}
+
+func (t *TokenStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	cacheKey := "revoked_token:" + id
+	n, err := t.rdb.Exists(ctx, cacheKey).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
@@ -43,3 +43,7 @@ func (u *UserStore) Set(ctx context.Context, user *store.User) error {
 
 	return u.rdb.SetEX(ctx, cacheKey, data, UserTimeExp).Err()
 }
+
+func (u *UserStore) Delete(ctx context.Context, id string) error {
+	return u.rdb.Del(ctx, "user:"+id).Err()
+}
@@ -2,8 +2,10 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -11,28 +13,51 @@ type CommentsStore struct {
 	db *pgxpool.Pool
 }
 
+// MaxCommentDepth is the deepest a reply can be nested; top-level comments
+// have depth 0.
+const MaxCommentDepth = 5
+
+var ErrMaxDepthExceeded = errors.New("comment thread is too deep")
+
 type Comment struct {
-	ID        string `json:"id"`
-	PostID    string `json:"post_id"`
-	AuthorID  string `json:"author_id"`
-	Content   string `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	User      User   `json:"user"`
+	ID         string     `json:"id"`
+	PostID     string     `json:"post_id"`
+	ParentID   *string    `json:"parent_id"`
+	Depth      int        `json:"depth"`
+	Path       []string   `json:"path,omitempty"`
+	ReplyCount int        `json:"reply_count"`
+	AuthorID   string     `json:"author_id"`
+	Content    string     `json:"content"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	HiddenAt   *time.Time `json:"hidden_at,omitempty"`
+	User       User       `json:"user"`
+}
+
+// ThreadQuery bounds how much of a comment tree is loaded at once. Threads
+// that are cut off can be paged through with ListReplies.
+type ThreadQuery struct {
+	RootLimit    int
+	RepliesLimit int
+	MaxDepth     int
 }
 
 func (c *CommentsStore) Create(ctx context.Context, comment *Comment) error {
 	query := `
-		INSERT INTO comments (post_id, author_id, content)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO comments (post_id, author_id, content, parent_id, depth)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
 	`
+	if comment.Depth > MaxCommentDepth {
+		return ErrMaxDepthExceeded
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	if err := c.db.QueryRow(ctx, query, comment.PostID, comment.AuthorID, comment.Content).
-		Scan(&comment.ID, &comment.CreatedAt); err != nil {
-		return err
+	if err := c.db.QueryRow(ctx, query, comment.PostID, comment.AuthorID, comment.Content, comment.ParentID, comment.Depth).
+		Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt); err != nil {
+		return mapPgError(err)
 	}
 
 	return nil
@@ -40,9 +65,9 @@ func (c *CommentsStore) Create(ctx context.Context, comment *Comment) error {
 
 func (c *CommentsStore) GetByPostID(ctx context.Context, postID string) ([]Comment, error) {
 	query := `
-		SELECT c.id, c.post_id, c.author_id, c.content, c.created_at, users.username, users.id FROM comments c
+		SELECT c.id, c.post_id, c.author_id, c.content, c.created_at, c.updated_at, users.username, users.id FROM comments c
 		JOIN users ON c.author_id = users.id
-		WHERE c.post_id = $1
+		WHERE c.post_id = $1 AND c.hidden_at IS NULL AND c.deleted_at IS NULL
 		ORDER BY c.created_at DESC
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -58,7 +83,228 @@ func (c *CommentsStore) GetByPostID(ctx context.Context, postID string) ([]Comme
 	for rows.Next() {
 		var comment Comment
 		comment.User = User{}
-		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.AuthorID, &comment.Content, &comment.CreatedAt, &comment.User.Username, &comment.User.ID); err != nil {
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.AuthorID, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt, &comment.User.Username, &comment.User.ID); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (c *CommentsStore) GetByID(ctx context.Context, id string) (*Comment, error) {
+	query := `
+		SELECT c.id, c.post_id, c.parent_id, c.depth, c.author_id, c.content, c.created_at, c.updated_at, c.hidden_at, users.username, users.id,
+			(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id AND r.hidden_at IS NULL AND r.deleted_at IS NULL) AS reply_count
+		FROM comments c
+		JOIN users ON c.author_id = users.id
+		WHERE c.id = $1 AND c.deleted_at IS NULL
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var comment Comment
+	err := c.db.QueryRow(ctx, query, id).
+		Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.Depth, &comment.AuthorID, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt, &comment.HiddenAt, &comment.User.Username, &comment.User.ID, &comment.ReplyCount)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, mapPgError(err)
+		}
+	}
+
+	return &comment, nil
+}
+
+// List returns a page of the post's top-level comments, newest first,
+// starting after the cursor when one is set.
+func (c *CommentsStore) List(ctx context.Context, postID string, cq PaginatedCursorQuery) ([]Comment, error) {
+	query := `
+		SELECT c.id, c.post_id, c.parent_id, c.depth, c.author_id, c.content, c.created_at, c.updated_at, users.username, users.id,
+			(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id AND r.hidden_at IS NULL AND r.deleted_at IS NULL) AS reply_count
+		FROM comments c
+		JOIN users ON c.author_id = users.id
+		WHERE
+			c.post_id = $1 AND
+			c.parent_id IS NULL AND
+			c.hidden_at IS NULL AND
+			c.deleted_at IS NULL AND
+			($2::timestamptz IS NULL OR (c.created_at, c.id) < ($2, $3::uuid))
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $4
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	createdAt, id := cq.Cursor.values()
+	rows, err := c.db.Query(ctx, query, postID, createdAt, id, cq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectComments(rows)
+}
+
+// ListReplies returns a page of the direct replies to a comment, oldest
+// first, starting after the cursor when one is set.
+func (c *CommentsStore) ListReplies(ctx context.Context, parentID string, cq PaginatedCursorQuery) ([]Comment, error) {
+	query := `
+		SELECT c.id, c.post_id, c.parent_id, c.depth, c.author_id, c.content, c.created_at, c.updated_at, users.username, users.id,
+			(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id AND r.hidden_at IS NULL AND r.deleted_at IS NULL) AS reply_count
+		FROM comments c
+		JOIN users ON c.author_id = users.id
+		WHERE
+			c.parent_id = $1 AND
+			c.hidden_at IS NULL AND
+			c.deleted_at IS NULL AND
+			($2::timestamptz IS NULL OR (c.created_at, c.id) > ($2, $3::uuid))
+		ORDER BY c.created_at, c.id
+		LIMIT $4
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	createdAt, id := cq.Cursor.values()
+	rows, err := c.db.Query(ctx, query, parentID, createdAt, id, cq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectComments(rows)
+}
+
+// GetThread returns the post's comment tree as a flattened, depth-first list.
+// Top-level comments are newest first and replies oldest first; each level
+// is capped by tq, and ReplyCount tells the client which threads have more
+// replies to page through.
+func (c *CommentsStore) GetThread(ctx context.Context, postID string, tq ThreadQuery) ([]Comment, error) {
+	query := `
+		WITH RECURSIVE thread AS (
+			SELECT root.id, ARRAY[root.id] AS path, ARRAY[root.position] AS sort_path
+			FROM (
+				SELECT id, ROW_NUMBER() OVER (ORDER BY created_at DESC, id DESC) AS position
+				FROM comments
+				WHERE post_id = $1 AND parent_id IS NULL AND hidden_at IS NULL AND deleted_at IS NULL
+				ORDER BY created_at DESC, id DESC
+				LIMIT $2
+			) root
+			UNION ALL
+			SELECT reply.id, t.path || reply.id, t.sort_path || reply.position
+			FROM thread t
+			CROSS JOIN LATERAL (
+				SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS position
+				FROM comments
+				WHERE parent_id = t.id AND hidden_at IS NULL AND deleted_at IS NULL
+				ORDER BY created_at, id
+				LIMIT $3
+			) reply
+			WHERE cardinality(t.path) <= $4
+		)
+		SELECT c.id, c.post_id, c.parent_id, c.depth, c.author_id, c.content, c.created_at, c.updated_at, users.username, users.id,
+			(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id AND r.hidden_at IS NULL AND r.deleted_at IS NULL) AS reply_count,
+			t.path
+		FROM thread t
+		JOIN comments c ON c.id = t.id
+		JOIN users ON c.author_id = users.id
+		ORDER BY t.sort_path
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := c.db.Query(ctx, query, postID, tq.RootLimit, tq.RepliesLimit, tq.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var comment Comment
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.Depth, &comment.AuthorID, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt, &comment.User.Username, &comment.User.ID, &comment.ReplyCount, &comment.Path); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (c *CommentsStore) Update(ctx context.Context, comment *Comment) error {
+	query := `
+		UPDATE comments
+		SET content = $1, updated_at = NOW()
+		WHERE id = $2 AND deleted_at IS NULL
+		RETURNING updated_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := c.db.QueryRow(ctx, query, comment.Content, comment.ID).Scan(&comment.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete soft-deletes the comment, which also hides its replies from
+// threads until it is restored or purged.
+func (c *CommentsStore) Delete(ctx context.Context, id, deletedBy string) error {
+	query := `
+		UPDATE comments
+		SET deleted_at = NOW(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING author_id
+	`
+	return softDelete(c.db, ctx, "comment", query, id, deletedBy)
+}
+
+// Restore undoes the soft deletion of a comment and records it in the audit
+// log.
+func (c *CommentsStore) Restore(ctx context.Context, id, restoredBy string) error {
+	query := `
+		UPDATE comments
+		SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+	return restore(c.db, ctx, "comment", query, id, restoredBy)
+}
+
+// Purge hard-deletes comments that were soft-deleted before the given time,
+// along with their replies.
+func (c *CommentsStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM comments
+		WHERE id IN (
+			SELECT id FROM comments
+			WHERE deleted_at < $1
+			LIMIT $2
+		)
+	`
+	return purge(c.db, ctx, query, before)
+}
+
+func collectComments(rows pgx.Rows) ([]Comment, error) {
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var comment Comment
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.Depth, &comment.AuthorID, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt, &comment.User.Username, &comment.User.ID, &comment.ReplyCount); err != nil {
 			return nil, err
 		}
 		comments = append(comments, comment)
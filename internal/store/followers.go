@@ -2,10 +2,11 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/lib/pq"
 )
 
 type FollowersStore struct {
@@ -18,32 +19,324 @@ type Follower struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-func (u *FollowersStore) Follow(ctx context.Context, followingID, id string) error {
+var ErrSelfFollow = errors.New("users cannot follow themselves")
+
+// Follow makes id follow followingID. When followingID is a private account a
+// follow request is recorded instead and requested is true. It returns
+// ErrSelfFollow when both are the same user, ErrNotFound when followingID does
+// not exist, ErrBlocked when either user blocked the other and ErrConflict
+// when the follow or request already exists.
+func (u *FollowersStore) Follow(ctx context.Context, followingID, id string) (requested bool, err error) {
+	if followingID == id {
+		return false, ErrSelfFollow
+	}
+
+	err = withTx(u.db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var private, following, blocked bool
+		err := tx.QueryRow(ctx, `
+			SELECT
+				u.is_private,
+				EXISTS (SELECT 1 FROM followers WHERE user_id = $2 AND following_id = u.id),
+				EXISTS (
+					SELECT 1 FROM user_blocks
+					WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)
+				)
+			FROM users u
+			WHERE u.id = $1
+			FOR SHARE
+		`, followingID, id).Scan(&private, &following, &blocked)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if blocked {
+			return ErrBlocked
+		}
+
+		if !private {
+			_, err := tx.Exec(ctx, `INSERT INTO followers (user_id, following_id) VALUES ($1, $2)`, id, followingID)
+			return mapPgError(err)
+		}
+
+		if following {
+			return ErrConflict
+		}
+
+		requested = true
+		_, err = tx.Exec(ctx, `INSERT INTO follow_requests (user_id, target_id) VALUES ($1, $2)`, id, followingID)
+		return mapPgError(err)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return requested, nil
+}
+
+// Unfollow removes the follow, or withdraws the pending follow request, from
+// id to followingID.
+func (u *FollowersStore) Unfollow(ctx context.Context, followingID, id string) error {
+	return withTx(u.db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.Exec(ctx, `DELETE FROM followers WHERE user_id = $1 AND following_id = $2`, id, followingID); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `DELETE FROM follow_requests WHERE user_id = $1 AND target_id = $2`, id, followingID)
+		return err
+	})
+}
+
+func (u *FollowersStore) CountFollowers(ctx context.Context, id string) (int, error) {
 	query := `
-		INSERT INTO followers (user_id, following_id)
-		VALUES ($1, $2)
+		SELECT COUNT(*) FROM followers
+		WHERE following_id = $1
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := u.db.Exec(ctx, query, id, followingID)
+	var count int
+	if err := u.db.QueryRow(ctx, query, id).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (u *FollowersStore) GetFollowerIDs(ctx context.Context, id string) ([]string, error) {
+	query := `
+		SELECT user_id FROM followers
+		WHERE following_id = $1
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := u.db.Query(ctx, query, id)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return ErrConflict
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// FilterFollowing returns the subset of candidateIDs that the user follows.
+func (u *FollowersStore) FilterFollowing(ctx context.Context, id string, candidateIDs []string) ([]string, error) {
+	query := `
+		SELECT following_id FROM followers
+		WHERE user_id = $1 AND following_id = ANY($2)
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := u.db.Query(ctx, query, id, candidateIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// FollowStats summarizes a user's follow graph as seen by a viewer.
+type FollowStats struct {
+	FollowerCount   int  `json:"follower_count"`
+	FollowingCount  int  `json:"following_count"`
+	IsFollowing     bool `json:"is_following"`
+	FollowRequested bool `json:"follow_requested"`
+}
+
+// FollowListEntry is a user appearing in a followers or following list.
+// FollowedAt is when the follow in the list was created, and IsFollowing
+// tells whether the viewer follows this user.
+type FollowListEntry struct {
+	ID          string    `json:"id"`
+	Username    string    `json:"username"`
+	FollowedAt  time.Time `json:"followed_at"`
+	IsFollowing bool      `json:"is_following"`
+}
+
+func (u *FollowersStore) GetStats(ctx context.Context, id, viewerID string) (*FollowStats, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM followers WHERE following_id = $1),
+			(SELECT COUNT(*) FROM followers WHERE user_id = $1),
+			EXISTS (SELECT 1 FROM followers WHERE user_id = $2 AND following_id = $1),
+			EXISTS (SELECT 1 FROM follow_requests WHERE user_id = $2 AND target_id = $1)
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var stats FollowStats
+	if err := u.db.QueryRow(ctx, query, id, viewerID).
+		Scan(&stats.FollowerCount, &stats.FollowingCount, &stats.IsFollowing, &stats.FollowRequested); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// ListFollowers returns the users following id, most recent follow first.
+func (u *FollowersStore) ListFollowers(ctx context.Context, id, viewerID string, pq PaginatedCursorQuery) ([]FollowListEntry, error) {
+	query := `
+		SELECT
+			u.id, u.username, f.created_at,
+			EXISTS (SELECT 1 FROM followers v WHERE v.user_id = $2 AND v.following_id = u.id)
+		FROM followers f
+		JOIN users u ON u.id = f.user_id
+		WHERE
+			f.following_id = $1 AND
+			($3::timestamptz IS NULL OR (f.created_at, f.user_id) < ($3, $4::uuid))
+		ORDER BY f.created_at DESC, f.user_id DESC
+		LIMIT $5
+	`
+	return u.list(ctx, query, id, viewerID, pq)
+}
+
+// ListFollowing returns the users id follows, most recent follow first.
+func (u *FollowersStore) ListFollowing(ctx context.Context, id, viewerID string, pq PaginatedCursorQuery) ([]FollowListEntry, error) {
+	query := `
+		SELECT
+			u.id, u.username, f.created_at,
+			EXISTS (SELECT 1 FROM followers v WHERE v.user_id = $2 AND v.following_id = u.id)
+		FROM followers f
+		JOIN users u ON u.id = f.following_id
+		WHERE
+			f.user_id = $1 AND
+			($3::timestamptz IS NULL OR (f.created_at, f.following_id) < ($3, $4::uuid))
+		ORDER BY f.created_at DESC, f.following_id DESC
+		LIMIT $5
+	`
+	return u.list(ctx, query, id, viewerID, pq)
+}
+
+// ListRequests returns the users waiting for id to approve their follow
+// request, most recent first. FollowedAt is when the request was made.
+func (u *FollowersStore) ListRequests(ctx context.Context, id string, pq PaginatedCursorQuery) ([]FollowListEntry, error) {
+	query := `
+		SELECT
+			u.id, u.username, r.created_at,
+			EXISTS (SELECT 1 FROM followers v WHERE v.user_id = $2 AND v.following_id = u.id)
+		FROM follow_requests r
+		JOIN users u ON u.id = r.user_id
+		WHERE
+			r.target_id = $1 AND
+			($3::timestamptz IS NULL OR (r.created_at, r.user_id) < ($3, $4::uuid))
+		ORDER BY r.created_at DESC, r.user_id DESC
+		LIMIT $5
+	`
+	return u.list(ctx, query, id, id, pq)
+}
+
+// ApproveRequest turns the pending request from requesterID into a follow of
+// id. It returns ErrNotFound when there is no such request.
+func (u *FollowersStore) ApproveRequest(ctx context.Context, id, requesterID string) error {
+	return withTx(u.db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		cmdTag, err := tx.Exec(ctx, `DELETE FROM follow_requests WHERE user_id = $1 AND target_id = $2`, requesterID, id)
+		if err != nil {
+			return mapPgError(err)
 		}
-		return err	
+		if cmdTag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO followers (user_id, following_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, requesterID, id)
+		return err
+	})
+}
+
+// RejectRequest discards the pending request from requesterID to follow id.
+// It returns ErrNotFound when there is no such request.
+func (u *FollowersStore) RejectRequest(ctx context.Context, id, requesterID string) error {
+	query := `
+		DELETE FROM follow_requests
+		WHERE user_id = $1 AND target_id = $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	cmdTag, err := u.db.Exec(ctx, query, requesterID, id)
+	if err != nil {
+		return mapPgError(err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return ErrNotFound
 	}
 	return nil
 }
 
-func (u *FollowersStore) Unfollow(ctx context.Context, followingID, id string) error {
+// CanView reports whether viewerID may see the content of ownerID: public
+// accounts are visible to everyone, private ones only to themselves and
+// their approved followers, and nothing is visible across a block.
+func (u *FollowersStore) CanView(ctx context.Context, viewerID, ownerID string) (bool, error) {
 	query := `
-		DELETE FROM followers
-		WHERE user_id = $1 AND following_id = $2
+		SELECT
+			u.id = $1 OR (
+				NOT EXISTS (
+					SELECT 1 FROM user_blocks b
+					WHERE (b.user_id = $1 AND b.blocked_id = u.id) OR (b.user_id = u.id AND b.blocked_id = $1)
+				) AND (
+					NOT u.is_private OR
+					EXISTS (SELECT 1 FROM followers f WHERE f.user_id = $1 AND f.following_id = u.id)
+				)
+			)
+		FROM users u
+		WHERE u.id = $2
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := u.db.Exec(ctx, query, id, followingID)
-	return err
+	var visible bool
+	if err := u.db.QueryRow(ctx, query, viewerID, ownerID).Scan(&visible); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return false, ErrNotFound
+		default:
+			return false, err
+		}
+	}
+
+	return visible, nil
+}
+
+func (u *FollowersStore) list(ctx context.Context, query, id, viewerID string, pq PaginatedCursorQuery) ([]FollowListEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	cursorCreatedAt, cursorID := pq.Cursor.values()
+	rows, err := u.db.Query(ctx, query, id, viewerID, cursorCreatedAt, cursorID, pq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []FollowListEntry{}
+	for rows.Next() {
+		var entry FollowListEntry
+		if err := rows.Scan(&entry.ID, &entry.Username, &entry.FollowedAt, &entry.IsFollowing); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
 }
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrSelfMute = errors.New("users cannot mute themselves")
+
+type MutesStore struct {
+	db *pgxpool.Pool
+}
+
+// Mute hides the posts of mutedID from the feed of id. Muting twice is not
+// an error.
+func (m *MutesStore) Mute(ctx context.Context, id, mutedID string) error {
+	if id == mutedID {
+		return ErrSelfMute
+	}
+
+	query := `
+		INSERT INTO user_mutes (user_id, muted_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := m.db.Exec(ctx, query, id, mutedID)
+	return mapPgError(err)
+}
+
+func (m *MutesStore) Unmute(ctx context.Context, id, mutedID string) error {
+	query := `
+		DELETE FROM user_mutes
+		WHERE user_id = $1 AND muted_id = $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := m.db.Exec(ctx, query, id, mutedID)
+	return err
+}
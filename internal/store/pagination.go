@@ -1,23 +1,32 @@
 package store
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PaginatedFeedQuery struct {
-	Limit  int    `validate:"min=1,max=20"`
+	Limit int `validate:"min=1,max=20"`
+	// Offset is only kept for clients that predate cursors; it cannot be
+	// combined with Cursor.
 	Offset int    `validate:"min=0"`
 	Sort   string `validate:"oneof=asc desc"`
 	Search string
 	Tags   []string
 	Since  *time.Time
 	Until  *time.Time
+	Cursor *Cursor
 }
 
-func (p PaginatedFeedQuery) Parse(ctx *gin.Context) (PaginatedFeedQuery, error) {
+func (p PaginatedFeedQuery) Parse(ctx *gin.Context, cursors CursorCodec) (PaginatedFeedQuery, error) {
 	limit := ctx.Query("limit")
 	if limit != "" {
 		l, err := strconv.Atoi(limit)
@@ -69,5 +78,168 @@ func (p PaginatedFeedQuery) Parse(ctx *gin.Context) (PaginatedFeedQuery, error)
 		p.Until = &u
 	}
 
+	cursor := ctx.Query("cursor")
+	if cursor != "" {
+		if p.Offset != 0 {
+			return p, errors.New("cursor and offset cannot be combined")
+		}
+		c, err := cursors.Decode(cursor)
+		if err != nil {
+			return p, err
+		}
+		p.Cursor = c
+	}
+
+	return p, nil
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the position of the last item of a page ordered by
+// (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// CursorCodec turns cursors into opaque tokens signed with HMAC-SHA256, so
+// clients cannot forge positions they were never handed.
+type CursorCodec struct {
+	secret []byte
+}
+
+func NewCursorCodec(secret []byte) CursorCodec {
+	return CursorCodec{secret: secret}
+}
+
+func (cc CursorCodec) Encode(c Cursor) string {
+	data, _ := json.Marshal(c)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(cc.sign(payload))
+}
+
+func (cc CursorCodec) Decode(s string) (*Cursor, error) {
+	payload, sig, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, cc.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+func (cc CursorCodec) sign(payload string) []byte {
+	h := hmac.New(sha256.New, cc.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// values returns the cursor as nullable query arguments.
+func (c *Cursor) values() (*time.Time, *string) {
+	if c == nil {
+		return nil, nil
+	}
+	return &c.CreatedAt, &c.ID
+}
+
+// PaginatedCursorQuery pages through a list ordered by (created_at, id)
+// using cursors only.
+type PaginatedCursorQuery struct {
+	Limit  int `validate:"min=1,max=50"`
+	Cursor *Cursor
+}
+
+func (p PaginatedCursorQuery) Parse(ctx *gin.Context, cursors CursorCodec) (PaginatedCursorQuery, error) {
+	limit := ctx.Query("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return p, err
+		}
+		p.Limit = l
+	}
+
+	cursor := ctx.Query("cursor")
+	if cursor != "" {
+		c, err := cursors.Decode(cursor)
+		if err != nil {
+			return p, err
+		}
+		p.Cursor = c
+	}
+
+	return p, nil
+}
+
+type SearchQuery struct {
+	Query  string `validate:"required,max=200"`
+	Type   string `validate:"oneof=all posts users"`
+	Limit  int    `validate:"min=1,max=50"`
+	Offset int    `validate:"min=0"`
+}
+
+func (p SearchQuery) Parse(ctx *gin.Context) (SearchQuery, error) {
+	p.Query = strings.TrimSpace(ctx.Query("q"))
+
+	searchType := ctx.Query("type")
+	if searchType != "" {
+		p.Type = searchType
+	}
+
+	limit := ctx.Query("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return p, err
+		}
+		p.Limit = l
+	}
+
+	offset := ctx.Query("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return p, err
+		}
+		p.Offset = o
+	}
+
+	return p, nil
+}
+
+type TrendingTagsQuery struct {
+	Window string `validate:"oneof=1h 24h 7d"`
+	Limit  int    `validate:"min=1,max=50"`
+}
+
+func (p TrendingTagsQuery) Parse(ctx *gin.Context) (TrendingTagsQuery, error) {
+	window := ctx.Query("window")
+	if window != "" {
+		p.Window = window
+	}
+
+	limit := ctx.Query("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return p, err
+		}
+		p.Limit = l
+	}
+
 	return p, nil
 }
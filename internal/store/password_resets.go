@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreatePasswordReset stores a reset token for the user that expires after
+// exp. Only the SHA-256 hash of the plain token is persisted, and any reset
+// the user requested earlier stops working.
+func (u *UsersStore) CreatePasswordReset(ctx context.Context, userID, token string, exp time.Duration) error {
+	return withTx(u.db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if err := u.usePasswordResets(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO password_resets (token, user_id, expires_at)
+			VALUES ($1, $2, $3)
+		`
+		_, err := tx.Exec(ctx, query, []byte(hashToken(token)), userID, time.Now().Add(exp))
+		return err
+	})
+}
+
+// ResetPassword sets a new password for the user the reset token was issued
+// to and uses up the token. Every refresh token of the user is revoked, so
+// existing sessions have to log in again; the IDs of the access tokens issued
+// alongside them are returned. Unknown, expired and used tokens are reported
+// as ErrNotFound.
+func (u *UsersStore) ResetPassword(ctx context.Context, token, newPassword string) (*User, []string, error) {
+	var pw password
+	if err := pw.Set(newPassword); err != nil {
+		return nil, nil, err
+	}
+
+	var user *User
+	var accessTokenIDs []string
+	err := withTx(u.db, ctx, func(tx pgx.Tx) error {
+		var err error
+		user, err = u.getByResetToken(ctx, tx, token)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.Exec(ctx, `UPDATE users SET password = $1 WHERE id = $2`, pw.hash, user.ID); err != nil {
+			return err
+		}
+
+		if err := u.usePasswordResets(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE refresh_tokens
+			SET revoked_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL
+			RETURNING access_token_id
+		`
+		rows, err := tx.Query(ctx, query, user.ID)
+		if err != nil {
+			return err
+		}
+
+		accessTokenIDs, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user.Password = pw
+	return user, accessTokenIDs, nil
+}
+
+// getByResetToken returns the user a usable reset token was issued to and
+// locks the token until the transaction ends, so it cannot be used twice.
+func (u *UsersStore) getByResetToken(ctx context.Context, tx pgx.Tx, token string) (*User, error) {
+	query := `
+		SELECT u.id, u.username, u.email, u.created_at
+		FROM users u
+		JOIN password_resets pr ON pr.user_id = u.id
+		WHERE pr.token = $1 AND pr.expires_at > $2 AND pr.used_at IS NULL
+		FOR UPDATE OF pr
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var user User
+	err := tx.QueryRow(ctx, query, []byte(hashToken(token)), time.Now()).
+		Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// usePasswordResets marks every outstanding reset token of the user as used.
+func (u *UsersStore) usePasswordResets(ctx context.Context, tx pgx.Tx, userID string) error {
+	query := `
+		UPDATE password_resets
+		SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	_, err := tx.Exec(ctx, query, userID)
+	return err
+}
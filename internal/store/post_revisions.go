@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PostRevision is a version of a post as it was saved. Previous holds the
+// version it replaced, and is nil for the first recorded version.
+type PostRevision struct {
+	ID        string        `json:"id"`
+	PostID    string        `json:"post_id"`
+	Version   int           `json:"version"`
+	Title     string        `json:"title"`
+	Content   string        `json:"content"`
+	Tags      []string      `json:"tags"`
+	EditedBy  *string       `json:"edited_by"`
+	CreatedAt time.Time     `json:"created_at"`
+	Previous  *PostRevision `json:"-"`
+}
+
+// ListRevisions returns a page of the post's revisions, newest first, each
+// with the revision it replaced.
+func (p *PostsStore) ListRevisions(ctx context.Context, postID string, pq PaginatedCursorQuery) ([]PostRevision, error) {
+	query := `
+		SELECT id, version, title, content, tags, edited_by, created_at,
+			prev_version, prev_title, prev_content, prev_tags
+		FROM (
+			SELECT r.*,
+				LAG(r.version) OVER w AS prev_version,
+				LAG(r.title) OVER w AS prev_title,
+				LAG(r.content) OVER w AS prev_content,
+				LAG(r.tags) OVER w AS prev_tags
+			FROM post_revisions r
+			WHERE r.post_id = $1
+			WINDOW w AS (ORDER BY r.version)
+		) r
+		WHERE $2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	createdAt, id := pq.Cursor.values()
+	rows, err := p.db.Query(ctx, query, postID, createdAt, id, pq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var (
+			r           PostRevision
+			prevVersion *int
+			prevTitle   *string
+			prevContent *string
+			prevTags    []string
+		)
+		err := rows.Scan(&r.ID, &r.Version, &r.Title, &r.Content, &r.Tags, &r.EditedBy, &r.CreatedAt,
+			&prevVersion, &prevTitle, &prevContent, &prevTags)
+		if err != nil {
+			return nil, err
+		}
+
+		r.PostID = postID
+		if prevVersion != nil {
+			r.Previous = &PostRevision{
+				PostID:  postID,
+				Version: *prevVersion,
+				Title:   *prevTitle,
+				Content: *prevContent,
+				Tags:    prevTags,
+			}
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// recordRevision stores the post at its current version. A version that is
+// already recorded is left unchanged.
+func recordRevision(ctx context.Context, tx pgx.Tx, post *Post, editedBy *string) error {
+	query := `
+		INSERT INTO post_revisions (post_id, version, title, content, tags, edited_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (post_id, version) DO NOTHING
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.Exec(ctx, query, post.ID, post.Version, post.Title, post.Content, post.Tags, editedBy, post.UpdatedAt)
+	return err
+}
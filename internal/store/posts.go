@@ -10,21 +10,37 @@ import (
 )
 
 type Post struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	AuthorID  string    `json:"author_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Version   int       `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      User      `json:"user"`
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	AuthorID  string     `json:"author_id"`
+	Tags      []string   `json:"tags"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Version   int        `json:"version"`
+	HiddenAt  *time.Time `json:"hidden_at,omitempty"`
+	Comments  []Comment  `json:"comments"`
+	User      User       `json:"user"`
 }
 
 type PostWithMetadata struct {
 	Post
-	CommentsCount int `json:"comments_count"`
+	CommentsCount  int            `json:"comments_count"`
+	ReactionCounts map[string]int `json:"reaction_counts"`
+	ViewerReaction *string        `json:"viewer_reaction"`
+}
+
+type PostSearchResult struct {
+	Post
+	Rank           float32 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+// TimelineEntry is the minimal reference to a post kept in a timeline.
+type TimelineEntry struct {
+	PostID    string
+	CreatedAt time.Time
 }
 
 type PostsStore struct {
@@ -51,102 +67,377 @@ func (p *PostsStore) Create(ctx context.Context, post *Post) error {
 
 func (p *PostsStore) GetByID(ctx context.Context, id string) (*Post, error) {
 	query := `
-		SELECT id, title, content, author_id, created_at, updated_at, tags, version
+		SELECT id, title, content, author_id, created_at, updated_at, tags, version, hidden_at
 		FROM posts
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	var post Post
 	err := p.db.QueryRow(ctx, query, id).
-		Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.UpdatedAt, &post.Tags, &post.Version)
+		Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.UpdatedAt, &post.Tags, &post.Version, &post.HiddenAt)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
 			return nil, ErrNotFound
 		default:
-			return nil, err
+			return nil, mapPgError(err)
 		}
 	}
 
 	return &post, nil
 }
 
-func (p *PostsStore) Update(ctx context.Context, post *Post) error {
+// Update saves the post if it is still at post.Version, and records the new
+// version in the post's revision history. It returns ErrVersionConflict when
+// the post was changed in the meantime.
+func (p *PostsStore) Update(ctx context.Context, post *Post, editedBy string) error {
+	return withTx(p.db, ctx, func(tx pgx.Tx) error {
+		query := `
+			SELECT title, content, tags, author_id, version, updated_at
+			FROM posts
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE
+		`
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var current Post
+		err := tx.QueryRow(ctx, query, post.ID).
+			Scan(&current.Title, &current.Content, &current.Tags, &current.AuthorID, &current.Version, &current.UpdatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if current.Version != post.Version {
+			return ErrVersionConflict
+		}
+
+		// posts that have never been edited have no revisions yet; only the
+		// author can have written the first version
+		var seedEditor *string
+		if current.Version == 1 {
+			seedEditor = &current.AuthorID
+		}
+		current.ID = post.ID
+		if err := recordRevision(ctx, tx, &current, seedEditor); err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE posts
+			SET title = $1, content = $2, tags = $3, updated_at = NOW(), version = version + 1
+			WHERE id = $4
+			RETURNING version, updated_at
+		`
+		err = tx.QueryRow(ctx, query, post.Title, post.Content, post.Tags, post.ID).
+			Scan(&post.Version, &post.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return recordRevision(ctx, tx, post, &editedBy)
+	})
+}
+
+// Delete soft-deletes the post; it stays restorable until it is purged.
+// Deletions by anyone other than the author are recorded in the audit log.
+func (p *PostsStore) Delete(ctx context.Context, id, deletedBy string) error {
+	query := `
+		UPDATE posts
+		SET deleted_at = NOW(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING author_id
+	`
+	return softDelete(p.db, ctx, "post", query, id, deletedBy)
+}
+
+// Restore undoes the soft deletion of a post and records it in the audit log.
+func (p *PostsStore) Restore(ctx context.Context, id, restoredBy string) error {
 	query := `
 		UPDATE posts
-		SET title = $1, content = $2, tags = $3, updated_at = NOW(), version = version + 1
-		WHERE id = $4 and version = $5
-		RETURNING version
+		SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+	return restore(p.db, ctx, "post", query, id, restoredBy)
+}
+
+// Purge hard-deletes posts that were soft-deleted before the given time,
+// along with their comments, and returns how many posts were removed.
+func (p *PostsStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM posts
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE deleted_at < $1
+			LIMIT $2
+		)
+	`
+	return purge(p.db, ctx, query, before)
+}
+
+// GetUserFeed returns the posts written by the user or by anyone the user
+// follows and has not muted. Each post appears at most once, and posts
+// sharing a created_at are ordered by id so pages are stable.
+func (p *PostsStore) GetUserFeed(ctx context.Context, userID string, fp PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	query := `
+		SELECT
+			p.id, p.title, p.content, p.tags, p.author_id, p.created_at, p.version,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL AND c.hidden_at IS NULL) AS comments_count,
+			u.username
+		FROM posts p
+		JOIN users u ON p.author_id = u.id
+		WHERE
+			p.hidden_at IS NULL AND
+			p.deleted_at IS NULL AND
+			(
+				p.author_id = $1 OR
+				EXISTS (SELECT 1 FROM followers f WHERE f.user_id = $1 AND f.following_id = p.author_id)
+			) AND
+			NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.user_id = $1 AND m.muted_id = p.author_id) AND
+			($4 = '' OR p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
+			(cardinality($5::text[]) = 0 OR p.tags @> $5) AND
+			($6::timestamptz IS NULL OR p.created_at >= $6) AND
+			($7::timestamptz IS NULL OR p.created_at <= $7) AND
+			(
+				$8::timestamptz IS NULL OR
+				($10 = 'desc' AND (p.created_at, p.id) < ($8, $9::uuid)) OR
+				($10 = 'asc' AND (p.created_at, p.id) > ($8, $9::uuid))
+			)
+		ORDER BY ` + feedOrderBy(fp.Sort) + `
+		LIMIT $2 OFFSET $3
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := p.db.QueryRow(ctx, query, post.Title, post.Content, post.Tags, post.ID, post.Version).
-		Scan(&post.Version)
+	tags := fp.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	cursorCreatedAt, cursorID := fp.Cursor.values()
+	rows, err := p.db.Query(ctx, query, userID, fp.Limit, fp.Offset, fp.Search, tags, fp.Since, fp.Until, cursorCreatedAt, cursorID, fp.Sort)
 	if err != nil {
-		switch {
-		case errors.Is(err, pgx.ErrNoRows):
-			return ErrNotFound
-		default:
-			return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostWithMetadata{}
+	for rows.Next() {
+		var post PostWithMetadata
+		post.User = User{}
+		err := rows.Scan(
+			&post.ID,
+			&post.Title,
+			&post.Content,
+			&post.Tags,
+			&post.AuthorID,
+			&post.CreatedAt,
+			&post.Version,
+			&post.CommentsCount,
+			&post.User.Username)
+		if err != nil {
+			return nil, err
 		}
+		posts = append(posts, post)
 	}
-	return nil
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := p.attachReactions(ctx, userID, posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
 }
 
-func (p *PostsStore) Delete(ctx context.Context, id string) error {
+// GetByTag returns the posts carrying the tag that the viewer may see,
+// ordered by creation time with the same cursor semantics as the feed.
+func (p *PostsStore) GetByTag(ctx context.Context, viewerID, tag string, fp PaginatedFeedQuery) ([]PostWithMetadata, error) {
 	query := `
-		DELETE FROM posts
-		WHERE id = $1
+		SELECT
+			p.id, p.title, p.content, p.tags, p.author_id, p.created_at, p.version,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL AND c.hidden_at IS NULL) AS comments_count,
+			u.username
+		FROM posts p
+		JOIN users u ON p.author_id = u.id
+		WHERE
+			p.tags @> ARRAY[$1::text] AND
+			p.hidden_at IS NULL AND
+			p.deleted_at IS NULL AND
+			` + visibleTo("$7") + ` AND
+			(
+				$4::timestamptz IS NULL OR
+				($6 = 'desc' AND (p.created_at, p.id) < ($4, $5::uuid)) OR
+				($6 = 'asc' AND (p.created_at, p.id) > ($4, $5::uuid))
+			)
+		ORDER BY ` + feedOrderBy(fp.Sort) + `
+		LIMIT $2 OFFSET $3
 	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	cursorCreatedAt, cursorID := fp.Cursor.values()
+	rows, err := p.db.Query(ctx, query, tag, fp.Limit, fp.Offset, cursorCreatedAt, cursorID, fp.Sort, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	posts := []PostWithMetadata{}
+	for rows.Next() {
+		var post PostWithMetadata
+		err := rows.Scan(
+			&post.ID,
+			&post.Title,
+			&post.Content,
+			&post.Tags,
+			&post.AuthorID,
+			&post.CreatedAt,
+			&post.Version,
+			&post.CommentsCount,
+			&post.User.Username)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := p.attachReactions(ctx, viewerID, posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// Search ranks the posts the viewer may see against a web-search style
+// query, weighting matches in the title above tags above content, and returns
+// highlighted excerpts marked with <mark>.
+func (p *PostsStore) Search(ctx context.Context, viewerID string, sq SearchQuery) ([]PostSearchResult, error) {
+	query := `
+		SELECT
+			p.id, p.title, p.content, p.tags, p.author_id, p.created_at, p.updated_at, p.version, u.username,
+			ts_rank_cd(p.search_vector, q) AS rank,
+			ts_headline('english', p.title, q, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>'),
+			ts_headline('english', p.content, q, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10')
+		FROM posts p
+		JOIN users u ON p.author_id = u.id,
+			websearch_to_tsquery('english', $1) q
+		WHERE
+			p.search_vector @@ q AND
+			p.hidden_at IS NULL AND
+			p.deleted_at IS NULL AND
+			` + visibleTo("$4") + `
+		ORDER BY rank DESC, p.created_at DESC, p.id DESC
+		LIMIT $2 OFFSET $3
+	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	cmdTag, err := p.db.Exec(ctx, query, id)
+	rows, err := p.db.Query(ctx, query, sq.Query, sq.Limit, sq.Offset, viewerID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if cmdTag.RowsAffected() == 0 {
-		return ErrNotFound
+	defer rows.Close()
+
+	results := []PostSearchResult{}
+	for rows.Next() {
+		var r PostSearchResult
+		err := rows.Scan(
+			&r.ID,
+			&r.Title,
+			&r.Content,
+			&r.Tags,
+			&r.AuthorID,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+			&r.Version,
+			&r.User.Username,
+			&r.Rank,
+			&r.TitleHighlight,
+			&r.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		r.User.ID = r.AuthorID
+		results = append(results, r)
 	}
-	return nil
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
 }
 
-func (p *PostsStore) GetUserFeed(ctx context.Context, userID string, fp PaginatedFeedQuery) ([]PostWithMetadata, error) {
+// visibleTo returns the condition under which the author u is visible to the
+// viewer bound to param, matching FollowersStore.CanView.
+func visibleTo(param string) string {
+	return `(
+		u.id = ` + param + ` OR (
+			NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.user_id = ` + param + ` AND b.blocked_id = u.id) OR (b.user_id = u.id AND b.blocked_id = ` + param + `)
+			) AND (
+				NOT u.is_private OR
+				EXISTS (SELECT 1 FROM followers f WHERE f.user_id = ` + param + ` AND f.following_id = u.id)
+			)
+		)
+	)`
+}
+
+// feedOrderBy maps the validated sort direction to a fixed ORDER BY clause,
+// so request input is never spliced into SQL.
+func feedOrderBy(sort string) string {
+	if sort == "asc" {
+		return "p.created_at ASC, p.id ASC"
+	}
+	return "p.created_at DESC, p.id DESC"
+}
+
+// GetFeedByIDs loads the posts with the given IDs as feed items, in the order
+// of ids. Timelines are cached, so the IDs are filtered again: posts that
+// were deleted or hidden since, and posts whose author the viewer muted,
+// blocked or can no longer see, are skipped.
+func (p *PostsStore) GetFeedByIDs(ctx context.Context, viewerID string, ids []string) ([]PostWithMetadata, error) {
 	query := `
 		SELECT
 			p.id, p.title, p.content, p.tags, p.author_id, p.created_at, p.version,
-			COUNT(c.id) AS comments_count, u.username
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL AND c.hidden_at IS NULL) AS comments_count,
+			u.username
 		FROM posts p
-		LEFT JOIN comments c ON p.id = c.post_id
-		LEFT JOIN users u ON p.author_id = u.id
-		JOIN followers f ON p.author_id = f.following_id or p.author_id = $1
-		WHERE 
-			f.user_id = $1 AND
-			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 = '{}') AND 
-			(p.created_at >= $6 OR $6 IS NULL) AND
-			(p.created_at <= $7 OR $7 IS NULL)
-		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + fp.Sort + `
-		LIMIT $2 OFFSET $3
+		JOIN users u ON p.author_id = u.id
+		WHERE
+			p.id = ANY($1) AND
+			p.hidden_at IS NULL AND
+			p.deleted_at IS NULL AND
+			NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.user_id = $2 AND m.muted_id = p.author_id) AND
+			` + visibleTo("$2") + `
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := p.db.Query(ctx, query, userID, fp.Limit, fp.Offset, fp.Search, fp.Tags, fp.Since, fp.Until)
+	rows, err := p.db.Query(ctx, query, ids, viewerID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	posts := []PostWithMetadata{}
+	byID := make(map[string]PostWithMetadata, len(ids))
 	for rows.Next() {
 		var post PostWithMetadata
-		post.User = User{}
 		err := rows.Scan(
 			&post.ID,
 			&post.Title,
@@ -160,12 +451,143 @@ func (p *PostsStore) GetUserFeed(ctx context.Context, userID string, fp Paginate
 		if err != nil {
 			return nil, err
 		}
-		posts = append(posts, post)
+		byID[post.ID] = post
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
+	posts := make([]PostWithMetadata, 0, len(byID))
+	for _, id := range ids {
+		if post, ok := byID[id]; ok {
+			posts = append(posts, post)
+		}
+	}
+
+	if err := p.attachReactions(ctx, viewerID, posts); err != nil {
+		return nil, err
+	}
+
 	return posts, nil
 }
+
+// GetTimelineEntries returns the newest posts of the user and of everyone the
+// user follows, used to build a timeline from scratch.
+func (p *PostsStore) GetTimelineEntries(ctx context.Context, userID string, limit int) ([]TimelineEntry, error) {
+	query := `
+		SELECT p.id, p.created_at
+		FROM posts p
+		WHERE
+			p.hidden_at IS NULL AND
+			p.deleted_at IS NULL AND
+			(
+				p.author_id = $1 OR
+				p.author_id IN (SELECT following_id FROM followers WHERE user_id = $1)
+			)
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := p.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectTimelineEntries(rows)
+}
+
+// GetEntriesByAuthors returns the newest posts of the given authors, starting
+// after the cursor when one is set.
+func (p *PostsStore) GetEntriesByAuthors(ctx context.Context, authorIDs []string, cursor *Cursor, limit int) ([]TimelineEntry, error) {
+	query := `
+		SELECT p.id, p.created_at
+		FROM posts p
+		WHERE
+			p.author_id = ANY($1) AND
+			p.hidden_at IS NULL AND
+			p.deleted_at IS NULL AND
+			($2::timestamptz IS NULL OR (p.created_at, p.id) < ($2, $3::uuid))
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $4
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	createdAt, id := cursor.values()
+	rows, err := p.db.Query(ctx, query, authorIDs, createdAt, id, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectTimelineEntries(rows)
+}
+
+func collectTimelineEntries(rows pgx.Rows) ([]TimelineEntry, error) {
+	defer rows.Close()
+
+	entries := []TimelineEntry{}
+	for rows.Next() {
+		var entry TimelineEntry
+		if err := rows.Scan(&entry.PostID, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// attachReactions loads the reaction counts and the viewer's own reaction for
+// a page of posts in a single query.
+func (p *PostsStore) attachReactions(ctx context.Context, viewerID string, posts []PostWithMetadata) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT post_id, type, COUNT(*), BOOL_OR(user_id = $2)
+		FROM post_reactions
+		WHERE post_id = ANY($1)
+		GROUP BY post_id, type
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	ids := make([]string, len(posts))
+	byID := make(map[string]*PostWithMetadata, len(posts))
+	for i := range posts {
+		posts[i].ReactionCounts = map[string]int{}
+		ids[i] = posts[i].ID
+		byID[posts[i].ID] = &posts[i]
+	}
+
+	rows, err := p.db.Query(ctx, query, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID, reactionType string
+		var count int
+		var reacted bool
+		if err := rows.Scan(&postID, &reactionType, &count, &reacted); err != nil {
+			return err
+		}
+
+		post := byID[postID]
+		post.ReactionCounts[reactionType] = count
+		if reacted {
+			post.ViewerReaction = &reactionType
+		}
+	}
+
+	return rows.Err()
+}
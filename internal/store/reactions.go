@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Reaction struct {
+	PostID    string    `json:"post_id"`
+	UserID    string    `json:"user_id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReactionsStore struct {
+	db *pgxpool.Pool
+}
+
+// Set records the user's reaction to a post, replacing any previous one.
+func (r *ReactionsStore) Set(ctx context.Context, reaction *Reaction) error {
+	query := `
+		INSERT INTO post_reactions (post_id, user_id, type)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (post_id, user_id) DO UPDATE
+		SET type = EXCLUDED.type, created_at = NOW()
+		RETURNING created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return r.db.QueryRow(ctx, query, reaction.PostID, reaction.UserID, reaction.Type).
+		Scan(&reaction.CreatedAt)
+}
+
+// Delete removes the user's reaction to a post. Removing a reaction that does
+// not exist is not an error.
+func (r *ReactionsStore) Delete(ctx context.Context, postID, userID string) error {
+	query := `
+		DELETE FROM post_reactions
+		WHERE post_id = $1 AND user_id = $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := r.db.Exec(ctx, query, postID, userID)
+	return err
+}
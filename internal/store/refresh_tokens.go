@@ -0,0 +1,183 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RefreshToken struct {
+	ID            string     `json:"id"`
+	Token         string     `json:"-"`
+	UserID        string     `json:"user_id"`
+	FamilyID      string     `json:"family_id"`
+	AccessTokenID string     `json:"access_token_id"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	UsedAt        *time.Time `json:"used_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type RefreshTokensStore struct {
+	db *pgxpool.Pool
+}
+
+// Create stores a new refresh token. Only the SHA-256 hash of the plain
+// token is persisted.
+func (r *RefreshTokensStore) Create(ctx context.Context, token *RefreshToken) error {
+	return withTx(r.db, ctx, func(tx pgx.Tx) error {
+		return r.create(ctx, tx, token)
+	})
+}
+
+// GetByToken returns the refresh token matching the plain token. Expired and
+// revoked tokens are reported as ErrNotFound.
+func (r *RefreshTokensStore) GetByToken(ctx context.Context, token string) (*RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, access_token_id, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token = $1 AND expires_at > $2 AND revoked_at IS NULL
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var rt RefreshToken
+	err := r.db.QueryRow(ctx, query, []byte(hashToken(token)), time.Now()).
+		Scan(&rt.ID, &rt.UserID, &rt.FamilyID, &rt.AccessTokenID, &rt.ExpiresAt, &rt.UsedAt, &rt.RevokedAt, &rt.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rt, nil
+}
+
+// Rotate exchanges the plain refresh token for next, which inherits the user
+// and family of the old token. Presenting a token that was already rotated
+// returns ErrTokenReused with next.UserID and next.FamilyID set, so the caller
+// can revoke the compromised family.
+func (r *RefreshTokensStore) Rotate(ctx context.Context, token string, next *RefreshToken) error {
+	return withTx(r.db, ctx, func(tx pgx.Tx) error {
+		query := `
+			SELECT id, user_id, family_id, expires_at, used_at, revoked_at
+			FROM refresh_tokens
+			WHERE token = $1
+			FOR UPDATE
+		`
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var current RefreshToken
+		err := tx.QueryRow(ctx, query, []byte(hashToken(token))).
+			Scan(&current.ID, &current.UserID, &current.FamilyID, &current.ExpiresAt, &current.UsedAt, &current.RevokedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		next.UserID = current.UserID
+		next.FamilyID = current.FamilyID
+
+		if current.RevokedAt != nil || !current.ExpiresAt.After(time.Now()) {
+			return ErrNotFound
+		}
+		if current.UsedAt != nil {
+			return ErrTokenReused
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, current.ID); err != nil {
+			return err
+		}
+
+		return r.create(ctx, tx, next)
+	})
+}
+
+// RevokeFamily revokes every refresh token in the family and returns the IDs
+// of the access tokens that were issued alongside them.
+func (r *RefreshTokensStore) RevokeFamily(ctx context.Context, familyID string) ([]string, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+		RETURNING access_token_id
+	`
+	return r.revoke(ctx, query, familyID)
+}
+
+// IsAccessTokenRevoked reports whether the access token with the given ID
+// belongs to a revoked refresh token family.
+func (r *RefreshTokensStore) IsAccessTokenRevoked(ctx context.Context, accessTokenID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM refresh_tokens
+			WHERE access_token_id = $1 AND revoked_at IS NOT NULL
+		)
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var revoked bool
+	if err := r.db.QueryRow(ctx, query, accessTokenID).Scan(&revoked); err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+func (r *RefreshTokensStore) create(ctx context.Context, tx pgx.Tx, token *RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (token, user_id, family_id, access_token_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return tx.QueryRow(ctx, query, []byte(hashToken(token.Token)), token.UserID, token.FamilyID, token.AccessTokenID, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *RefreshTokensStore) revoke(ctx context.Context, query string, id string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accessTokenIDs := []string{}
+	for rows.Next() {
+		var accessTokenID string
+		if err := rows.Scan(&accessTokenID); err != nil {
+			return nil, err
+		}
+		accessTokenIDs = append(accessTokenIDs, accessTokenID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return accessTokenIDs, nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,331 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrReportNotClaimed = errors.New("report must be claimed by the moderator resolving it")
+	ErrInvalidAction    = errors.New("action does not apply to the reported content")
+)
+
+type Report struct {
+	ID         string     `json:"id"`
+	ReporterID string     `json:"reporter_id"`
+	TargetType string     `json:"target_type"`
+	TargetID   string     `json:"target_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	ClaimedBy  *string    `json:"claimed_by"`
+	ClaimedAt  *time.Time `json:"claimed_at"`
+	Action     *string    `json:"action"`
+	ResolvedBy *string    `json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Resolution is the outcome a moderator picks for a report. SuspendedUntil is
+// only used by the suspend action.
+type Resolution struct {
+	Action         string
+	Note           string
+	SuspendedUntil *time.Time
+}
+
+// reportTargets maps each reportable target type to the query returning the
+// user responsible for it.
+var reportTargets = map[string]string{
+	"post":    `SELECT author_id FROM posts WHERE id = $1 AND deleted_at IS NULL`,
+	"comment": `SELECT author_id FROM comments WHERE id = $1 AND deleted_at IS NULL`,
+	"user":    `SELECT id FROM users WHERE id = $1`,
+}
+
+const reportColumns = `
+	id, reporter_id, target_type, target_id, reason, details, status,
+	claimed_by, claimed_at, action, resolved_by, resolved_at, created_at
+`
+
+type ReportsStore struct {
+	db *pgxpool.Pool
+}
+
+// Create files a report against an existing post, comment or user. It returns
+// ErrNotFound when the target does not exist and ErrConflict when the
+// reporter already has an unresolved report against it.
+func (r *ReportsStore) Create(ctx context.Context, report *Report) error {
+	return withTx(r.db, ctx, func(tx pgx.Tx) error {
+		if _, err := reportSubject(ctx, tx, report.TargetType, report.TargetID); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO reports (reporter_id, target_type, target_id, reason, details)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, status, created_at
+		`
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRow(ctx, query, report.ReporterID, report.TargetType, report.TargetID, report.Reason, report.Details).
+			Scan(&report.ID, &report.Status, &report.CreatedAt)
+		return mapPgError(err)
+	})
+}
+
+func (r *ReportsStore) GetByID(ctx context.Context, id string) (*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	report, err := scanReport(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// List returns a page of the reports with the given status, oldest first, so
+// the queue is worked through in the order reports came in.
+func (r *ReportsStore) List(ctx context.Context, status string, pq PaginatedCursorQuery) ([]Report, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM reports
+		WHERE
+			status = $1 AND
+			($2::timestamptz IS NULL OR (created_at, id) > ($2, $3::uuid))
+		ORDER BY created_at, id
+		LIMIT $4
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	createdAt, id := pq.Cursor.values()
+	rows, err := r.db.Query(ctx, query, status, createdAt, id, pq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// Claim assigns an open report to the moderator. Claiming a report the
+// moderator already holds is not an error; a report claimed by someone else
+// or already resolved returns ErrConflict.
+func (r *ReportsStore) Claim(ctx context.Context, id, moderatorID string) (*Report, error) {
+	var report *Report
+	err := withTx(r.db, ctx, func(tx pgx.Tx) error {
+		query := `
+			UPDATE reports
+			SET status = 'claimed', claimed_by = $2, claimed_at = COALESCE(claimed_at, NOW())
+			WHERE id = $1 AND (status = 'open' OR (status = 'claimed' AND claimed_by = $2))
+			RETURNING ` + reportColumns
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var err error
+		report, err = scanReport(tx.QueryRow(ctx, query, id, moderatorID))
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+
+			var exists bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM reports WHERE id = $1)`, id).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return ErrNotFound
+			}
+			return ErrConflict
+		}
+
+		return recordAudit(ctx, tx, &AuditEntry{
+			ActorID:    &moderatorID,
+			Action:     "report.claim",
+			TargetType: "report",
+			TargetID:   report.ID,
+			ReportID:   &report.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Resolve applies the resolution to the reported content and closes the
+// report, recording the action in the audit log. The report must be claimed
+// by the moderator. It returns the resolved report and the ID of the user
+// responsible for the reported content.
+func (r *ReportsStore) Resolve(ctx context.Context, id, moderatorID string, res Resolution) (*Report, string, error) {
+	var report *Report
+	var subjectID string
+	err := withTx(r.db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var err error
+		report, err = scanReport(tx.QueryRow(ctx, `SELECT `+reportColumns+` FROM reports WHERE id = $1 FOR UPDATE`, id))
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if report.Status == "resolved" {
+			return ErrConflict
+		}
+		if report.Status != "claimed" || report.ClaimedBy == nil || *report.ClaimedBy != moderatorID {
+			return ErrReportNotClaimed
+		}
+
+		// a dismissal still resolves reports whose target has since been
+		// deleted; every other action needs the target to exist
+		subjectID, err = reportSubject(ctx, tx, report.TargetType, report.TargetID)
+		if err != nil && (res.Action != "dismiss" || !errors.Is(err, ErrNotFound)) {
+			return err
+		}
+
+		entry := &AuditEntry{
+			ActorID:    &moderatorID,
+			TargetType: report.TargetType,
+			TargetID:   report.TargetID,
+			ReportID:   &report.ID,
+			Details:    res.Note,
+		}
+
+		switch res.Action {
+		case "dismiss":
+			entry.Action = "report.dismiss"
+		case "hide":
+			if err := hideTarget(ctx, tx, report.TargetType, report.TargetID); err != nil {
+				return err
+			}
+			entry.Action = report.TargetType + ".hide"
+		case "warn":
+			entry.Action = "user.warn"
+			entry.TargetType, entry.TargetID = "user", subjectID
+		case "suspend":
+			if res.SuspendedUntil == nil {
+				return ErrInvalidAction
+			}
+			if _, err := tx.Exec(ctx, `UPDATE users SET suspended_until = $1 WHERE id = $2`, res.SuspendedUntil, subjectID); err != nil {
+				return err
+			}
+			entry.Action = "user.suspend"
+			entry.TargetType, entry.TargetID = "user", subjectID
+		default:
+			return ErrInvalidAction
+		}
+
+		query := `
+			UPDATE reports
+			SET status = 'resolved', action = $2, resolved_by = $3, resolved_at = NOW()
+			WHERE id = $1
+			RETURNING ` + reportColumns
+		report, err = scanReport(tx.QueryRow(ctx, query, report.ID, res.Action, moderatorID))
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, entry)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return report, subjectID, nil
+}
+
+// reportSubject returns the user responsible for a report target, or
+// ErrNotFound when the target does not exist.
+func reportSubject(ctx context.Context, tx pgx.Tx, targetType, targetID string) (string, error) {
+	query, ok := reportTargets[targetType]
+	if !ok {
+		return "", ErrInvalidAction
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var subjectID string
+	if err := tx.QueryRow(ctx, query, targetID).Scan(&subjectID); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return "", ErrNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return subjectID, nil
+}
+
+func hideTarget(ctx context.Context, tx pgx.Tx, targetType, targetID string) error {
+	var query string
+	switch targetType {
+	case "post":
+		query = `UPDATE posts SET hidden_at = NOW() WHERE id = $1 AND hidden_at IS NULL`
+	case "comment":
+		query = `UPDATE comments SET hidden_at = NOW() WHERE id = $1 AND hidden_at IS NULL`
+	default:
+		return ErrInvalidAction
+	}
+
+	_, err := tx.Exec(ctx, query, targetID)
+	return err
+}
+
+func scanReport(row pgx.Row) (*Report, error) {
+	var report Report
+	err := row.Scan(
+		&report.ID,
+		&report.ReporterID,
+		&report.TargetType,
+		&report.TargetID,
+		&report.Reason,
+		&report.Details,
+		&report.Status,
+		&report.ClaimedBy,
+		&report.ClaimedAt,
+		&report.Action,
+		&report.ResolvedBy,
+		&report.ResolvedAt,
+		&report.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// rolesChannel is notified by a trigger whenever roles or their permissions
+// change.
+const rolesChannel = "roles_changed"
+
+type rolePermissions map[int]map[string]struct{}
+
+// RoleRegistry keeps the permissions of every role in memory so
+// authorization checks do not hit Postgres. It is replaced wholesale on
+// reload, so lookups need no locking.
+type RoleRegistry struct {
+	db          *pgxpool.Pool
+	roles       *RolesStore
+	permissions atomic.Pointer[rolePermissions]
+}
+
+func NewRoleRegistry(db *pgxpool.Pool) *RoleRegistry {
+	return &RoleRegistry{db: db, roles: &RolesStore{db}}
+}
+
+// Reload reads every role and its permissions from Postgres. The previous
+// registry stays in use if they cannot be read.
+func (r *RoleRegistry) Reload(ctx context.Context) error {
+	roles, err := r.roles.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	permissions := make(rolePermissions, len(roles))
+	for _, role := range roles {
+		granted := make(map[string]struct{}, len(role.Permissions))
+		for _, p := range role.Permissions {
+			granted[p] = struct{}{}
+		}
+		permissions[role.ID] = granted
+	}
+
+	r.permissions.Store(&permissions)
+	return nil
+}
+
+// HasPermission reports whether the role has been granted the permission.
+// It reports false until the registry has been loaded.
+func (r *RoleRegistry) HasPermission(roleID int, permission string) bool {
+	permissions := r.permissions.Load()
+	if permissions == nil {
+		return false
+	}
+
+	_, ok := (*permissions)[roleID][permission]
+	return ok
+}
+
+// Listen reloads the registry whenever roles change, until ctx is done or
+// the connection fails. It reloads once after subscribing so changes made
+// while it was not listening are picked up.
+func (r *RoleRegistry) Listen(ctx context.Context) error {
+	pooled, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the connection keeps its subscription, so it must not go back to the
+	// pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+rolesChannel); err != nil {
+		return err
+	}
+
+	if err := r.Reload(ctx); err != nil {
+		return err
+	}
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		if err := r.Reload(ctx); err != nil {
+			return err
+		}
+	}
+}
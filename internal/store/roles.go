@@ -2,14 +2,30 @@ package store
 
 import (
 	"context"
+	"errors"
+	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrLastRoleManager   = errors.New("at least one role must keep the role:manage permission")
+)
+
 type Role struct {
-	ID          int    `json:"id"`
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Level       int      `json:"level"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Permission is a named action, such as post:delete:any, that can be
+// granted to roles.
+type Permission struct {
 	Name        string `json:"name"`
-	Level       int    `json:"level"`
 	Description string `json:"description"`
 }
 
@@ -27,9 +43,175 @@ func (r *RolesStore) GetByName(ctx context.Context, roleName string) (*Role, err
 	role := &Role{}
 	err := r.db.QueryRow(ctx, query, roleName).
 		Scan(&role.ID, &role.Name, &role.Description, &role.Level)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return role, nil
+}
+
+// List returns every role with the permissions granted to it, lowest level
+// first.
+func (r *RolesStore) List(ctx context.Context) ([]Role, error) {
+	query := `
+		SELECT r.id, r.name, COALESCE(r.description, ''), r.level,
+			ARRAY(SELECT rp.permission FROM role_permissions rp WHERE rp.role_id = r.id ORDER BY rp.permission)
+		FROM roles r
+		ORDER BY r.level, r.id
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.Level, &role.Permissions); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *RolesStore) ListPermissions(ctx context.Context) ([]Permission, error) {
+	query := `
+		SELECT name, description FROM permissions ORDER BY name
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []Permission{}
+	for rows.Next() {
+		var p Permission
+		if err := rows.Scan(&p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// SetPermissions replaces the permissions granted to the role and records
+// the change in the audit log. It returns ErrUnknownPermission when any of
+// the permissions does not exist, and ErrLastRoleManager when no role would
+// be left with role:manage.
+func (r *RolesStore) SetPermissions(ctx context.Context, roleName string, permissions []string, setBy string) (*Role, error) {
+	role, err := r.GetByName(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withTx(r.db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var known int
+		query := `SELECT COUNT(*) FROM permissions WHERE name = ANY($1)`
+		if err := tx.QueryRow(ctx, query, permissions).Scan(&known); err != nil {
+			return err
+		}
+		if known != len(permissions) {
+			return ErrUnknownPermission
+		}
+
+		// concurrent changes must not each remove role:manage from a
+		// different role while still seeing the other's grant, so they run
+		// one at a time
+		if _, err := tx.Exec(ctx, `LOCK TABLE role_permissions IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, role.ID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO role_permissions (role_id, permission)
+			SELECT $1, unnest($2::text[])
+		`
+		if _, err := tx.Exec(ctx, query, role.ID, permissions); err != nil {
+			return err
+		}
+
+		// without role:manage nobody could grant it back short of editing
+		// the database
+		var managed bool
+		query = `SELECT EXISTS (SELECT 1 FROM role_permissions WHERE permission = 'role:manage')`
+		if err := tx.QueryRow(ctx, query).Scan(&managed); err != nil {
+			return err
+		}
+		if !managed {
+			return ErrLastRoleManager
+		}
+
+		return recordAudit(ctx, tx, &AuditEntry{
+			ActorID:    &setBy,
+			Action:     "role.permissions",
+			TargetType: "role",
+			TargetID:   role.Name,
+			Details:    strings.Join(permissions, ","),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	role.Permissions = permissions
 	return role, nil
 }
+
+// Assign gives the user the role and records the change in the audit log.
+func (r *RolesStore) Assign(ctx context.Context, userID, roleName, assignedBy string) error {
+	role, err := r.GetByName(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	return withTx(r.db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		cmdTag, err := tx.Exec(ctx, `UPDATE users SET role_id = $1 WHERE id = $2`, role.ID, userID)
+		if err != nil {
+			return err
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		return recordAudit(ctx, tx, &AuditEntry{
+			ActorID:    &assignedBy,
+			Action:     "user.role",
+			TargetType: "user",
+			TargetID:   userID,
+			Details:    role.Name,
+		})
+	})
+}
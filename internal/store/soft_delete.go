@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// purgeBatchSize bounds how many rows a single purge statement deletes, so
+// a large backlog does not hold locks for long.
+const purgeBatchSize = 1000
+
+// softDelete runs an UPDATE that marks the row deleted and returns its
+// author_id, recording an audit entry when someone other than the author
+// deleted it.
+func softDelete(db *pgxpool.Pool, ctx context.Context, targetType, query, id, deletedBy string) error {
+	return withTx(db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var authorID string
+		if err := tx.QueryRow(ctx, query, id, deletedBy).Scan(&authorID); err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if authorID == deletedBy {
+			return nil
+		}
+
+		return recordAudit(ctx, tx, &AuditEntry{
+			ActorID:    &deletedBy,
+			Action:     targetType + ".delete",
+			TargetType: targetType,
+			TargetID:   id,
+		})
+	})
+}
+
+// restore runs an UPDATE that clears the deletion of a row and records it in
+// the audit log.
+func restore(db *pgxpool.Pool, ctx context.Context, targetType, query, id, restoredBy string) error {
+	return withTx(db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		cmdTag, err := tx.Exec(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		return recordAudit(ctx, tx, &AuditEntry{
+			ActorID:    &restoredBy,
+			Action:     targetType + ".restore",
+			TargetType: targetType,
+			TargetID:   id,
+		})
+	})
+}
+
+// purge runs a batched DELETE until no rows are left, and returns the total
+// number of rows removed.
+func purge(db *pgxpool.Pool, ctx context.Context, query string, before time.Time) (int64, error) {
+	var total int64
+	for {
+		n, err := func() (int64, error) {
+			ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+			defer cancel()
+
+			cmdTag, err := db.Exec(ctx, query, before, purgeBatchSize)
+			if err != nil {
+				return 0, err
+			}
+			return cmdTag.RowsAffected(), nil
+		}()
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		if n < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -3,25 +3,38 @@ package store
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
-	ErrNotFound          = errors.New("resource not found")
-	QueryTimeoutDuration = 5 * time.Second
-	ErrConflict          = errors.New("resource already exists")
+	ErrNotFound            = errors.New("resource not found")
+	QueryTimeoutDuration   = 5 * time.Second
+	ErrConflict            = errors.New("resource already exists")
+	ErrTokenReused         = errors.New("refresh token already used")
+	ErrConstraintViolation = errors.New("resource violates a constraint")
+	ErrVersionConflict     = errors.New("resource was modified concurrently")
 )
 
 type Store struct {
 	Posts interface {
 		Create(context.Context, *Post) error
 		GetByID(context.Context, string) (*Post, error)
-		Delete(context.Context, string) error
-		Update(context.Context, *Post) error
+		Delete(context.Context, string, string) error
+		Restore(context.Context, string, string) error
+		Purge(context.Context, time.Time) (int64, error)
+		Update(context.Context, *Post, string) error
+		ListRevisions(context.Context, string, PaginatedCursorQuery) ([]PostRevision, error)
 		GetUserFeed(context.Context, string, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetFeedByIDs(context.Context, string, []string) ([]PostWithMetadata, error)
+		GetTimelineEntries(context.Context, string, int) ([]TimelineEntry, error)
+		GetEntriesByAuthors(context.Context, []string, *Cursor, int) ([]TimelineEntry, error)
+		Search(context.Context, string, SearchQuery) ([]PostSearchResult, error)
+		GetByTag(context.Context, string, string, PaginatedFeedQuery) ([]PostWithMetadata, error)
 	}
 	Users interface {
 		Create(context.Context, pgx.Tx, *User) error
@@ -30,27 +43,126 @@ type Store struct {
 		GetByID(context.Context, string) (*User, error)
 		GetByEmail(context.Context, string) (*User, error)
 		Activate(context.Context, string) error
+		SearchByUsername(context.Context, string, int, int) ([]User, error)
+		SetPrivate(context.Context, string, bool) ([]string, error)
+		CreatePasswordReset(context.Context, string, string, time.Duration) error
+		ResetPassword(context.Context, string, string) (*User, []string, error)
 	}
 	Comments interface {
 		Create(context.Context, *Comment) error
 		GetByPostID(context.Context, string) ([]Comment, error)
+		GetByID(context.Context, string) (*Comment, error)
+		List(context.Context, string, PaginatedCursorQuery) ([]Comment, error)
+		ListReplies(context.Context, string, PaginatedCursorQuery) ([]Comment, error)
+		GetThread(context.Context, string, ThreadQuery) ([]Comment, error)
+		Update(context.Context, *Comment) error
+		Delete(context.Context, string, string) error
+		Restore(context.Context, string, string) error
+		Purge(context.Context, time.Time) (int64, error)
 	}
 	Followers interface {
-		Follow(context.Context, string, string) error
+		Follow(context.Context, string, string) (bool, error)
 		Unfollow(context.Context, string, string) error
+		CountFollowers(context.Context, string) (int, error)
+		GetFollowerIDs(context.Context, string) ([]string, error)
+		FilterFollowing(context.Context, string, []string) ([]string, error)
+		GetStats(context.Context, string, string) (*FollowStats, error)
+		ListFollowers(context.Context, string, string, PaginatedCursorQuery) ([]FollowListEntry, error)
+		ListFollowing(context.Context, string, string, PaginatedCursorQuery) ([]FollowListEntry, error)
+		ListRequests(context.Context, string, PaginatedCursorQuery) ([]FollowListEntry, error)
+		ApproveRequest(context.Context, string, string) error
+		RejectRequest(context.Context, string, string) error
+		CanView(context.Context, string, string) (bool, error)
+	}
+	Blocks interface {
+		Block(context.Context, string, string) error
+		Unblock(context.Context, string, string) error
+		IsBlocked(context.Context, string, string) (bool, error)
+	}
+	Mutes interface {
+		Mute(context.Context, string, string) error
+		Unmute(context.Context, string, string) error
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
+		List(context.Context) ([]Role, error)
+		ListPermissions(context.Context) ([]Permission, error)
+		SetPermissions(context.Context, string, []string, string) (*Role, error)
+		Assign(context.Context, string, string, string) error
+	}
+	Reactions interface {
+		Set(context.Context, *Reaction) error
+		Delete(context.Context, string, string) error
+	}
+	Tags interface {
+		Trending(context.Context, TrendingTagsQuery) ([]TrendingTag, error)
+		PruneCounts(context.Context) (int64, error)
+	}
+	Reports interface {
+		Create(context.Context, *Report) error
+		GetByID(context.Context, string) (*Report, error)
+		List(context.Context, string, PaginatedCursorQuery) ([]Report, error)
+		Claim(context.Context, string, string) (*Report, error)
+		Resolve(context.Context, string, string, Resolution) (*Report, string, error)
+	}
+	AuditLog interface {
+		List(context.Context, PaginatedCursorQuery) ([]AuditEntry, error)
+	}
+	RefreshTokens interface {
+		Create(context.Context, *RefreshToken) error
+		GetByToken(context.Context, string) (*RefreshToken, error)
+		Rotate(context.Context, string, *RefreshToken) error
+		RevokeFamily(context.Context, string) ([]string, error)
+		IsAccessTokenRevoked(context.Context, string) (bool, error)
 	}
 }
 
 func NewStore(db *pgxpool.Pool) Store {
 	return Store{
-		Posts:     &PostsStore{db},
-		Users:     &UsersStore{db},
-		Comments:  &CommentsStore{db},
-		Followers: &FollowersStore{db},
-		Roles:     &RolesStore{db},
+		Posts:         &PostsStore{db},
+		Users:         &UsersStore{db},
+		Comments:      &CommentsStore{db},
+		Followers:     &FollowersStore{db},
+		Blocks:        &BlocksStore{db},
+		Mutes:         &MutesStore{db},
+		Roles:         &RolesStore{db},
+		Reactions:     &ReactionsStore{db},
+		Tags:          &TagsStore{db},
+		Reports:       &ReportsStore{db},
+		AuditLog:      &AuditLogStore{db},
+		RefreshTokens: &RefreshTokensStore{db},
+	}
+}
+
+// SQLSTATE codes of the integrity constraint violations mapped by mapPgError.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+	pgInvalidText         = "22P02"
+)
+
+// mapPgError translates integrity constraint violations into the store's
+// errors. A foreign key violation means a referenced row does not exist, so
+// it is reported as ErrNotFound, as is an ID that is not a valid UUID since
+// no row can have it. Other errors are returned unchanged.
+func mapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return fmt.Errorf("%w: %s", ErrConflict, pgErr.ConstraintName)
+	case pgForeignKeyViolation:
+		return fmt.Errorf("%w: %s", ErrNotFound, pgErr.ConstraintName)
+	case pgCheckViolation:
+		return fmt.Errorf("%w: %s", ErrConstraintViolation, pgErr.ConstraintName)
+	case pgInvalidText:
+		return fmt.Errorf("%w: %s", ErrNotFound, pgErr.Message)
+	default:
+		return err
 	}
 }
 
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TrendingTag struct {
+	Tag   string  `json:"tag"`
+	Posts int     `json:"posts"`
+	Score float64 `json:"score"`
+}
+
+// trendingWindows maps each supported window to its length and to the
+// half-life used to decay older counts inside it.
+var trendingWindows = map[string]struct {
+	length   time.Duration
+	halfLife time.Duration
+}{
+	"1h":  {time.Hour, 15 * time.Minute},
+	"24h": {24 * time.Hour, 6 * time.Hour},
+	"7d":  {7 * 24 * time.Hour, 24 * time.Hour},
+}
+
+type TagsStore struct {
+	db *pgxpool.Pool
+}
+
+// Trending ranks tags by their hourly post counts inside the window, halving
+// the weight of a bucket for every half-life it is old.
+func (t *TagsStore) Trending(ctx context.Context, tq TrendingTagsQuery) ([]TrendingTag, error) {
+	query := `
+		SELECT
+			tag,
+			SUM(count)::int AS posts,
+			SUM(count * power(0.5, GREATEST(EXTRACT(EPOCH FROM NOW() - bucket), 0) / $2))::float8 AS score
+		FROM tag_counts
+		WHERE bucket >= date_trunc('hour', NOW() - $1::interval)
+		GROUP BY tag
+		HAVING SUM(count) > 0
+		ORDER BY score DESC, tag
+		LIMIT $3
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	window := trendingWindows[tq.Window]
+	rows, err := t.db.Query(ctx, query, window.length, window.halfLife.Seconds(), tq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TrendingTag{}
+	for rows.Next() {
+		var tag TrendingTag
+		if err := rows.Scan(&tag.Tag, &tag.Posts, &tag.Score); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// PruneCounts deletes the hourly counts older than the longest trending
+// window, which no ranking reads any more, and returns how many were removed.
+func (t *TagsStore) PruneCounts(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM tag_counts
+		WHERE bucket < date_trunc('hour', NOW() - $1::interval)
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var longest time.Duration
+	for _, window := range trendingWindows {
+		longest = max(longest, window.length)
+	}
+
+	cmdTag, err := t.db.Exec(ctx, query, longest)
+	if err != nil {
+		return 0, err
+	}
+
+	return cmdTag.RowsAffected(), nil
+}
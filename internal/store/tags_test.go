@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestPruneCountsKeepsTrendingWindows(t *testing.T) {
+	db := newTestDB(t)
+	tags := &TagsStore{db}
+	ctx := context.Background()
+
+	query := `
+		INSERT INTO tag_counts (tag, bucket, count)
+		VALUES
+			('recent', date_trunc('hour', NOW() - interval '1 hour'), 1),
+			('week', date_trunc('hour', NOW() - interval '6 days'), 1),
+			('stale', date_trunc('hour', NOW() - interval '8 days'), 1)
+	`
+	if _, err := db.Exec(ctx, query); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := tags.PruneCounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Errorf("pruned %d buckets, want 1", pruned)
+	}
+
+	rows, err := db.Query(ctx, `SELECT tag FROM tag_counts ORDER BY tag`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 2 || left[0] != "recent" || left[1] != "week" {
+		t.Errorf("tag counts left = %v, want [recent week]", left)
+	}
+}
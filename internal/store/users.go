@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -13,14 +14,21 @@ import (
 )
 
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	RoleID    int64     `json:"role_id"`
-	CreatedAt time.Time `json:"created_at"`
-	Activated bool      `json:"activated"`
-	Role      Role      `json:"role"`
+	ID             string     `json:"id"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	Password       password   `json:"-"`
+	RoleID         int64      `json:"role_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Activated      bool       `json:"activated"`
+	Private        bool       `json:"private"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	Role           Role       `json:"role"`
+}
+
+// IsSuspended reports whether a moderator has suspended the user.
+func (u *User) IsSuspended() bool {
+	return u.SuspendedUntil != nil && u.SuspendedUntil.After(time.Now())
 }
 
 type password struct {
@@ -38,6 +46,10 @@ func (p *password) Set(text string) error {
 	return nil
 }
 
+func (p *password) Compare(text string) error {
+	return bcrypt.CompareHashAndPassword(p.hash, []byte(text))
+}
+
 type UsersStore struct {
 	db *pgxpool.Pool
 }
@@ -59,7 +71,7 @@ func (u *UsersStore) Create(ctx context.Context, tx pgx.Tx, user *User) error {
 
 	if err := tx.QueryRow(ctx, query, user.Username, user.Email, user.Password.hash, role).
 		Scan(&user.ID, &user.CreatedAt); err != nil {
-		return err
+		return mapPgError(err)
 	}
 
 	return nil
@@ -67,7 +79,7 @@ func (u *UsersStore) Create(ctx context.Context, tx pgx.Tx, user *User) error {
 
 func (u *UsersStore) GetByID(ctx context.Context, id string) (*User, error) {
 	query := `
-		SELECT users.id, username, email, password, created_at, roles.*
+		SELECT users.id, username, email, password, created_at, is_private, suspended_until, roles.*
 		FROM users
 		JOIN roles ON users.role_id = roles.id
 		WHERE users.id = $1
@@ -77,13 +89,13 @@ func (u *UsersStore) GetByID(ctx context.Context, id string) (*User, error) {
 
 	var user User
 	err := u.db.QueryRow(ctx, query, id).
-		Scan(&user.ID, &user.Username, &user.Email, &user.Password.hash, &user.CreatedAt, &user.Role.ID, &user.Role.Name, &user.Role.Description, &user.Role.Level)
+		Scan(&user.ID, &user.Username, &user.Email, &user.Password.hash, &user.CreatedAt, &user.Private, &user.SuspendedUntil, &user.Role.ID, &user.Role.Name, &user.Role.Description, &user.Role.Level)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
 			return nil, ErrNotFound
 		default:
-			return nil, err
+			return nil, mapPgError(err)
 		}
 	}
 
@@ -92,7 +104,7 @@ func (u *UsersStore) GetByID(ctx context.Context, id string) (*User, error) {
 
 func (u *UsersStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, username, email, password, created_at
+		SELECT id, username, email, password, created_at, suspended_until
 		FROM users
 		WHERE email = $1 AND activated = true
 	`
@@ -101,7 +113,7 @@ func (u *UsersStore) GetByEmail(ctx context.Context, email string) (*User, error
 
 	var user User
 	err := u.db.QueryRow(ctx, query, email).
-		Scan(&user.ID, &user.Username, &user.Email, &user.Password.hash, &user.CreatedAt)
+		Scan(&user.ID, &user.Username, &user.Email, &user.Password.hash, &user.CreatedAt, &user.SuspendedUntil)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
@@ -114,6 +126,104 @@ func (u *UsersStore) GetByEmail(ctx context.Context, email string) (*User, error
 	return &user, nil
 }
 
+// SearchByUsername returns activated users whose username starts with
+// prefix. The range bounds let idx_users_username serve the lookup.
+func (u *UsersStore) SearchByUsername(ctx context.Context, prefix string, limit, offset int) ([]User, error) {
+	query := `
+		SELECT id, username, created_at
+		FROM users
+		WHERE
+			username >= $1 AND username < $2 AND
+			starts_with(username, $1) AND
+			activated = true
+		ORDER BY username
+		LIMIT $3 OFFSET $4
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := u.db.Query(ctx, query, prefix, prefixUpperBound(prefix), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Username, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// prefixUpperBound returns the smallest string greater than every string that
+// starts with prefix.
+func prefixUpperBound(prefix string) string {
+	runes := []rune(prefix)
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] < utf8.MaxRune {
+			runes[i]++
+			return string(runes[:i+1])
+		}
+	}
+	return string(utf8.MaxRune)
+}
+
+// SetPrivate changes whether the user's content is limited to approved
+// followers. Making an account public approves every pending follow request;
+// the IDs of those requesters are returned.
+func (u *UsersStore) SetPrivate(ctx context.Context, id string, private bool) ([]string, error) {
+	var approved []string
+	err := withTx(u.db, ctx, func(tx pgx.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		cmdTag, err := tx.Exec(ctx, `UPDATE users SET is_private = $1 WHERE id = $2`, private, id)
+		if err != nil {
+			return err
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		if private {
+			return nil
+		}
+
+		query := `
+			WITH approved AS (
+				DELETE FROM follow_requests
+				WHERE target_id = $1
+				RETURNING user_id, target_id
+			)
+			INSERT INTO followers (user_id, following_id)
+			SELECT user_id, target_id FROM approved
+			ON CONFLICT DO NOTHING
+			RETURNING user_id
+		`
+		rows, err := tx.Query(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		approved, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return approved, nil
+}
+
 func (u *UsersStore) CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration) error {
 	return withTx(u.db, ctx, func(tx pgx.Tx) error {
 		if err := u.Create(ctx, tx, user); err != nil {
@@ -20,8 +20,12 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"expvar"
+	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 	"time"
 
 	"github.com/cprakhar/gopher-social/internal/auth"
@@ -46,16 +50,23 @@ const version = "1.0.1"
 
 func main() {
 
-	cfg := config.Load()
+	// Logger
+	logger := zap.Must(zap.NewProduction()).Sugar()
+	defer logger.Sync()
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Panic(err)
+	}
 	cfg.Version = version
 
+	if err := cfg.Validate(); err != nil {
+		logger.Panic(err)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Logger
-	logger := zap.Must(zap.NewProduction()).Sugar()
-	defer logger.Sync()
-
 	// Database connection pool
 	db, err := db.New(ctx,
 		cfg.DB.Addr,
@@ -76,23 +87,67 @@ func main() {
 
 	logger.Info("database connection pool established")
 
-	rateLimiter := ratelimiter.NewFixedWindowLimiter(cfg.RateLimiter.RequestsPerTimeFrame, cfg.RateLimiter.TimeFrame)
+	rateLimiter, err := ratelimiter.New(cfg.RateLimiter, rdb)
+	if err != nil {
+		logger.Panic(err)
+	}
 
-	store := store.NewStore(db)
+	lockoutBackend := "memory"
+	if cfg.Redis.Enabled {
+		lockoutBackend = "redis"
+	}
+	accountLockout := ratelimiter.NewLockout(lockoutBackend, rdb, cfg.Auth.Login.MaxTracked, cfg.Auth.Login.Account)
+	ipLockout := ratelimiter.NewLockout(lockoutBackend, rdb, cfg.Auth.Login.MaxTracked, cfg.Auth.Login.IP)
+
+	cursorSecret := []byte(cfg.CursorSecret)
+	if len(cursorSecret) == 0 {
+		// cursors will not survive restarts or work across replicas
+		logger.Warn("CURSOR_SECRET is not set, using a random pagination cursor secret")
+		cursorSecret = make([]byte, 32)
+		if _, err := rand.Read(cursorSecret); err != nil {
+			logger.Panic(err)
+		}
+	}
+	cursors := store.NewCursorCodec(cursorSecret)
 
-	jwtAuthenticator := auth.NewJWTAuthenticator(cfg.Auth.Token.Secret, cfg.Auth.Token.Aud, cfg.Auth.Token.Iss)
+	roles := store.NewRoleRegistry(db)
+	if err := roles.Reload(ctx); err != nil {
+		logger.Panic(err)
+	}
+	go watchRoles(roles, cfg.Roles, logger)
+
+	store := store.NewStore(db)
+	go purgeDeleted(store, cfg.SoftDelete, logger)
+	go pruneTagCounts(store, cfg.Tags, logger)
+
+	var authenticator auth.Authenticator
+	if cfg.Auth.Token.KeySet != "" {
+		keySetAuthenticator, err := auth.NewKeySetAuthenticator(cfg.Auth.Token.KeySet, cfg.Auth.Token.Aud, cfg.Auth.Token.Iss)
+		if err != nil {
+			logger.Panic(err)
+		}
+		go reloadKeySetOnSignal(keySetAuthenticator, logger)
+		authenticator = keySetAuthenticator
+		logger.Infow("token keyset loaded", "path", cfg.Auth.Token.KeySet)
+	} else {
+		authenticator = auth.NewJWTAuthenticator(cfg.Auth.Token.Secret, cfg.Auth.Token.Aud, cfg.Auth.Token.Iss)
+	}
 
 	mailer := mail.NewSendGrid(cfg.Mail.Sender, cfg.Mail.ApiKey)
 	app := &application{
 		config: cfg,
 		handler: handler.Handler{
-			Cfg:           cfg,
-			Store:         store,
-			Logger:        logger,
-			Mailer:        mailer,
-			Authenticator: jwtAuthenticator,
-			CacheStorage:  cache.NewRedisStore(rdb),
-			RateLimiter:   rateLimiter,
+			Cfg:            cfg,
+			Store:          store,
+			Logger:         logger,
+			Mailer:         mailer,
+			Authenticator:  authenticator,
+			CacheStorage:   cache.NewRedisStore(rdb),
+			RateLimiter:    rateLimiter,
+			AccountLockout: accountLockout,
+			IPLockout:      ipLockout,
+			Cursors:        cursors,
+			Roles:          roles,
 		},
 		logger: logger,
 	}
@@ -108,5 +163,23 @@ func main() {
 	}
 
 	mux := app.mount()
+	if err := app.checkRateLimitRoutes(mux); err != nil {
+		logger.Panic(err)
+	}
 	logger.Fatal(app.run(mux))
 }
+
+// reloadKeySetOnSignal re-reads the token keyset from disk on SIGHUP so keys
+// can be rotated without a restart.
+func reloadKeySetOnSignal(k *auth.KeySetAuthenticator, logger *zap.SugaredLogger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if err := k.Reload(); err != nil {
+			logger.Errorw("error reloading token keyset", "error", err)
+			continue
+		}
+		logger.Info("token keyset reloaded")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cprakhar/gopher-social/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+func TestReloadKeySetOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"one.pem", "two.pem"} {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "keyset.json")
+	writeKeySet := func(active string) {
+		manifest := fmt.Sprintf(`{"active": %q, "keys": [
+			{"kid": "one", "alg": "EdDSA", "path": "one.pem"},
+			{"kid": "two", "alg": "EdDSA", "path": "two.pem"}
+		]}`, active)
+		if err := os.WriteFile(path, []byte(manifest), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	activeKid := func(k *auth.KeySetAuthenticator) string {
+		tokenString, err := k.GenerateToken(jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		kid, _ := token.Header["kid"].(string)
+		return kid
+	}
+
+	writeKeySet("one")
+	k, err := auth.NewKeySetAuthenticator(path, "aud", "issuer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keep SIGHUP from terminating the test binary before the reloader has
+	// registered for it
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	go reloadKeySetOnSignal(k, zap.NewNop().Sugar())
+
+	writeKeySet("two")
+	deadline := time.Now().Add(5 * time.Second)
+	for activeKid(k) != "two" {
+		if time.Now().After(deadline) {
+			t.Fatal("keyset was not reloaded after SIGHUP")
+		}
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,6 @@
+ALTER TABLE comments
+ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE comments SET updated_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_created_at ON comments(post_id, created_at DESC, id DESC);
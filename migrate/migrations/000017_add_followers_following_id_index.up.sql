@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_followers_following_id ON followers(following_id);
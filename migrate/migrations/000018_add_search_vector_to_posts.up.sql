@@ -0,0 +1,23 @@
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+-- array_to_string is not immutable, so the vector is maintained by a trigger
+-- rather than a generated column
+CREATE OR REPLACE FUNCTION posts_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', coalesce(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(array_to_string(NEW.tags, ' '), '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(NEW.content, '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_search_vector_trigger ON posts;
+CREATE TRIGGER posts_search_vector_trigger
+BEFORE INSERT OR UPDATE OF title, content, tags ON posts
+FOR EACH ROW EXECUTE FUNCTION posts_search_vector_update();
+
+UPDATE posts SET title = title;
+
+CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING gin (search_vector);
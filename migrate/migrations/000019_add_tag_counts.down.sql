@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS posts_tag_counts_trigger ON posts;
+DROP FUNCTION IF EXISTS tag_counts_update();
+
+DROP TABLE IF EXISTS tag_counts;
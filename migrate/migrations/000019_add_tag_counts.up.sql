@@ -0,0 +1,43 @@
+-- hourly post counts per tag, kept up to date by a trigger so trending tags
+-- can be ranked without scanning posts
+CREATE TABLE IF NOT EXISTS tag_counts (
+    tag TEXT NOT NULL,
+    bucket TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tag, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tag_counts_bucket ON tag_counts (bucket);
+
+-- removals only update existing rows, so buckets that have been pruned are
+-- never recreated with a negative count
+CREATE OR REPLACE FUNCTION tag_counts_update() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.tags IS NOT NULL THEN
+        UPDATE tag_counts
+        SET count = count - 1
+        WHERE bucket = date_trunc('hour', OLD.created_at)
+          AND tag IN (SELECT DISTINCT unnest(OLD.tags));
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.tags IS NOT NULL THEN
+        INSERT INTO tag_counts (tag, bucket, count)
+        SELECT DISTINCT t, date_trunc('hour', NEW.created_at), 1
+        FROM unnest(NEW.tags) AS t
+        ON CONFLICT (tag, bucket) DO UPDATE SET count = tag_counts.count + 1;
+    END IF;
+
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_tag_counts_trigger ON posts;
+CREATE TRIGGER posts_tag_counts_trigger
+AFTER INSERT OR DELETE OR UPDATE OF tags ON posts
+FOR EACH ROW EXECUTE FUNCTION tag_counts_update();
+
+INSERT INTO tag_counts (tag, bucket, count)
+SELECT t, date_trunc('hour', p.created_at), COUNT(DISTINCT p.id)
+FROM posts p, unnest(p.tags) AS t
+GROUP BY t, date_trunc('hour', p.created_at)
+ON CONFLICT (tag, bucket) DO NOTHING;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_followers_user_id_created_at;
+DROP INDEX IF EXISTS idx_followers_following_id_created_at;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_followers_following_id_created_at ON followers (following_id, created_at DESC, user_id DESC);
+CREATE INDEX IF NOT EXISTS idx_followers_user_id_created_at ON followers (user_id, created_at DESC, following_id DESC);
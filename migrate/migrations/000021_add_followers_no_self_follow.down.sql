@@ -0,0 +1,2 @@
+ALTER TABLE followers
+DROP CONSTRAINT IF EXISTS followers_no_self_follow;
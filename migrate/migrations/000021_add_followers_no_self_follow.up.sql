@@ -0,0 +1,10 @@
+DELETE FROM followers WHERE user_id = following_id;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'followers_no_self_follow') THEN
+        ALTER TABLE followers
+        ADD CONSTRAINT followers_no_self_follow CHECK (user_id <> following_id);
+    END IF;
+END
+$$;
@@ -0,0 +1,12 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS is_private BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, target_id),
+    CONSTRAINT follow_requests_no_self_request CHECK (user_id <> target_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_requests_target_id_created_at ON follow_requests (target_id, created_at DESC, user_id DESC);
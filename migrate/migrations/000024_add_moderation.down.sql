@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS suspended_until;
+
+ALTER TABLE comments
+DROP COLUMN IF EXISTS hidden_at;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS hidden_at;
@@ -0,0 +1,35 @@
+CREATE OR REPLACE FUNCTION tag_counts_update() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.tags IS NOT NULL THEN
+        UPDATE tag_counts
+        SET count = count - 1
+        WHERE bucket = date_trunc('hour', OLD.created_at)
+          AND tag IN (SELECT DISTINCT unnest(OLD.tags));
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.tags IS NOT NULL THEN
+        INSERT INTO tag_counts (tag, bucket, count)
+        SELECT DISTINCT t, date_trunc('hour', NEW.created_at), 1
+        FROM unnest(NEW.tags) AS t
+        ON CONFLICT (tag, bucket) DO UPDATE SET count = tag_counts.count + 1;
+    END IF;
+
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_tag_counts_trigger ON posts;
+CREATE TRIGGER posts_tag_counts_trigger
+AFTER INSERT OR DELETE OR UPDATE OF tags ON posts
+FOR EACH ROW EXECUTE FUNCTION tag_counts_update();
+
+DROP INDEX IF EXISTS idx_comments_deleted_at;
+DROP INDEX IF EXISTS idx_posts_deleted_at;
+
+ALTER TABLE comments
+DROP COLUMN IF EXISTS deleted_by,
+DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS deleted_by,
+DROP COLUMN IF EXISTS deleted_at;
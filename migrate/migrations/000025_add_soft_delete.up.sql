@@ -0,0 +1,38 @@
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE comments
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- only deleted rows are indexed; the purge job is the only reader
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- deleted posts stop counting towards trending tags, and count again if they
+-- are restored
+CREATE OR REPLACE FUNCTION tag_counts_update() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.tags IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        UPDATE tag_counts
+        SET count = count - 1
+        WHERE bucket = date_trunc('hour', OLD.created_at)
+          AND tag IN (SELECT DISTINCT unnest(OLD.tags));
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.tags IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        INSERT INTO tag_counts (tag, bucket, count)
+        SELECT DISTINCT t, date_trunc('hour', NEW.created_at), 1
+        FROM unnest(NEW.tags) AS t
+        ON CONFLICT (tag, bucket) DO UPDATE SET count = tag_counts.count + 1;
+    END IF;
+
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_tag_counts_trigger ON posts;
+CREATE TRIGGER posts_tag_counts_trigger
+AFTER INSERT OR DELETE OR UPDATE OF tags, deleted_at ON posts
+FOR EACH ROW EXECUTE FUNCTION tag_counts_update();
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission)
+);
+
+INSERT INTO permissions (name, description) VALUES
+('post:update:any', 'Edit posts written by other users'),
+('post:delete:any', 'Delete posts written by other users'),
+('post:restore', 'Restore deleted posts'),
+('comment:update:any', 'Edit comments written by other users'),
+('comment:delete:any', 'Delete comments written by other users'),
+('comment:restore', 'Restore deleted comments'),
+('content:view:hidden', 'See posts and comments hidden by moderation'),
+('user:view:private', 'See the profiles and posts of private accounts'),
+('report:moderate', 'List, claim and resolve reports'),
+('audit_log:read', 'Read the audit log'),
+('role:manage', 'Change role permissions and assign roles to users')
+ON CONFLICT (name) DO NOTHING;
+
+-- grants match what the role levels allowed before permissions existed
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.name
+FROM roles r
+JOIN permissions p ON
+    (r.name = 'moderator' AND p.name IN (
+        'post:update:any', 'comment:update:any', 'comment:delete:any',
+        'content:view:hidden', 'user:view:private', 'report:moderate', 'audit_log:read'
+    ))
+    OR r.name = 'admin'
+ON CONFLICT DO NOTHING;
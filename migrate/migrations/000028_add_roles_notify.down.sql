@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS role_permissions_notify_trigger ON role_permissions;
+DROP TRIGGER IF EXISTS roles_notify_trigger ON roles;
+DROP FUNCTION IF EXISTS notify_roles_changed();
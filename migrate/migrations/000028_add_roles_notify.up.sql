@@ -0,0 +1,17 @@
+-- signal every API instance to reload its role registry
+CREATE OR REPLACE FUNCTION notify_roles_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('roles_changed', '');
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS roles_notify_trigger ON roles;
+CREATE TRIGGER roles_notify_trigger
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON roles
+FOR EACH STATEMENT EXECUTE FUNCTION notify_roles_changed();
+
+DROP TRIGGER IF EXISTS role_permissions_notify_trigger ON role_permissions;
+CREATE TRIGGER role_permissions_notify_trigger
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON role_permissions
+FOR EACH STATEMENT EXECUTE FUNCTION notify_roles_changed();
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/cprakhar/gopher-social/internal/config"
+	"github.com/cprakhar/gopher-social/internal/store"
+	"go.uber.org/zap"
+)
+
+// purgeDeleted hard-deletes posts and comments whose soft deletion is older
+// than the retention period, once per purge interval.
+func purgeDeleted(s store.Store, cfg config.SoftDeleteConfig, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx := context.Background()
+		before := time.Now().Add(-cfg.Retention)
+
+		posts, err := s.Posts.Purge(ctx, before)
+		if err != nil {
+			logger.Errorw("error purging deleted posts", "error", err)
+		}
+
+		comments, err := s.Comments.Purge(ctx, before)
+		if err != nil {
+			logger.Errorw("error purging deleted comments", "error", err)
+		}
+
+		if posts > 0 || comments > 0 {
+			logger.Infow("purged deleted content", "posts", posts, "comments", comments)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/cprakhar/gopher-social/internal/config"
+	"github.com/cprakhar/gopher-social/internal/store"
+	"go.uber.org/zap"
+)
+
+// rolesListenRetry is how long to wait before listening for role changes
+// again after the connection was lost.
+const rolesListenRetry = 5 * time.Second
+
+// watchRoles keeps the role registry in sync with Postgres. Changes are
+// picked up through LISTEN/NOTIFY, with a periodic reload in case a
+// notification is missed while reconnecting.
+func watchRoles(roles *store.RoleRegistry, cfg config.RolesConfig, logger *zap.SugaredLogger) {
+	go func() {
+		for {
+			err := roles.Listen(context.Background())
+			logger.Errorw("error listening for role changes", "error", err)
+			time.Sleep(rolesListenRetry)
+		}
+	}()
+
+	ticker := time.NewTicker(cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := roles.Reload(context.Background()); err != nil {
+			logger.Errorw("error reloading roles", "error", err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/cprakhar/gopher-social/internal/config"
+	"github.com/cprakhar/gopher-social/internal/store"
+	"go.uber.org/zap"
+)
+
+// pruneTagCounts deletes the hourly tag counts that no trending window reads
+// any more, once per prune interval.
+func pruneTagCounts(s store.Store, cfg config.TagsConfig, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(cfg.PruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		pruned, err := s.Tags.PruneCounts(context.Background())
+		if err != nil {
+			logger.Errorw("error pruning tag counts", "error", err)
+			continue
+		}
+
+		if pruned > 0 {
+			logger.Infow("pruned tag counts", "buckets", pruned)
+		}
+	}
+}